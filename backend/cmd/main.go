@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -54,15 +55,27 @@ func main() {
 
 	// Инициализация сервисов
 	authService := service.NewAuthService(accountRepo, os.Getenv("mysecretkey"))
-	backendService := service.NewBackendService(rabbitRepo, dbRepo)
+	grace := 5 * time.Minute
+	if value := os.Getenv("CONTAINER_GRACE_PERIOD"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			log.Fatal("CONTAINER_GRACE_PERIOD must be a positive number of seconds")
+		}
+		grace = time.Duration(seconds) * time.Second
+	}
+	backendService := service.NewBackendService(rabbitRepo, dbRepo, grace)
 
 	// Запуск потребителя RabbitMQ
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	var wg sync.WaitGroup
-	wg.Add(1)
+	wg.Add(5)
 	go backendService.StartConsuming(ctx, &wg)
+	go backendService.StartConsumingStats(ctx, &wg)
+	go backendService.StartConsumingEvents(ctx, &wg)
+	go backendService.StartConsumingServiceHealth(ctx, &wg)
+	go backendService.StartStaleWatcher(ctx, &wg)
 
 	// Инициализация HTTP-сервера
 	e := echo.New()
@@ -83,6 +96,26 @@ func main() {
 	protected := e.Group("/protected")
 	protected.Use(handler.AuthMiddleware)
 	protected.GET("/containers", handler.GetContainers)
+	protected.GET("/inventory", handler.GetInventory)
+	protected.GET("/hosts", handler.GetHosts)
+	protected.GET("/services", handler.GetServices)
+	protected.GET("/services/:id/health", handler.GetServiceHealth)
+	protected.GET("/containers/:id/probes", handler.GetProbeResults)
+	protected.POST("/containers/:id/probe", handler.ProbeContainer)
+	protected.POST("/containers/:id/diagnostics", handler.RunDiagnostics)
+	protected.GET("/containers/:id/diagnostics", handler.GetDiagnostics)
+	protected.GET("/containers/:id/pings", handler.GetPingHistory)
+	protected.GET("/containers/:id/stats", handler.GetContainerStats)
+	protected.GET("/containers/:id/events", handler.GetContainerEvents)
+	protected.GET("/containers/:id/addresses", handler.GetAddressHistory)
+	protected.GET("/timeline", handler.GetTimeline)
+	protected.GET("/monitor/rules", handler.GetMonitorRules)
+	protected.POST("/monitor/rules", handler.CreateMonitorRule)
+	protected.PUT("/monitor/rules/:id", handler.UpdateMonitorRule)
+	protected.DELETE("/monitor/rules/:id", handler.DeleteMonitorRule)
+
+	// Конфигурация мониторинга для пингеров
+	e.GET("/monitor/config", handler.GetMonitorConfig, handler.PingerAuthMiddleware)
 
 	// Запуск HTTP-сервера в отдельной горутине
 	go func() {
@@ -1,6 +1,11 @@
 package domain
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 type Account struct {
 	ID       int    `db:"id" json:"id"`
@@ -14,16 +19,425 @@ type LoginRequest struct {
 }
 
 type PingResult struct {
+	ContainerID string    `json:"container_id"`
+	Host        string    `json:"host"`
 	IP          string    `json:"ip"`
+	Network     string    `json:"network"`
+	Family      string    `json:"family"`
+	Timestamp   time.Time `json:"timestamp"`
 	PingTime    float64   `json:"ping_time"`
 	Status      bool      `json:"status"`
 	LastSuccess time.Time `json:"last_success"`
+	// Reason уточняет причину неудачи, см. ReasonNoSharedNetwork
+	Reason string `json:"reason"`
+	// EnvironmentHealthy — доступны ли пингеру опорные цели; nil, если они не настроены
+	EnvironmentHealthy *bool `json:"environment_healthy,omitempty"`
+	// Mode: external — проверка из пингера, netns — изнутри сетевого пространства контейнера
+	Mode  string          `json:"mode"`
+	Netns json.RawMessage `json:"netns,omitempty"`
+	// Probes — результаты всех проверок контейнера по этому адресу
+	Probes []ProbeResult `json:"probes,omitempty"`
+	// Container — метаданные контейнера, присылаемые пингером
+	Container *ContainerMeta `json:"container,omitempty"`
+}
+
+// ContainerMeta — запись инвентаря. Down выставляется, если контейнер исчез
+// или не работает дольше периода ожидания.
+type ContainerMeta struct {
+	ID             string    `db:"container_id" json:"id"`
+	Identity       string    `db:"identity" json:"identity"`
+	Source         string    `db:"source" json:"source"`
+	Host           string    `db:"host" json:"host"`
+	Name           string    `db:"name" json:"name"`
+	Image          string    `db:"image" json:"image"`
+	ImageDigest    string    `db:"image_digest" json:"image_digest"`
+	ComposeProject string    `db:"compose_project" json:"compose_project"`
+	ComposeService string    `db:"compose_service" json:"compose_service"`
+	Labels         Labels    `db:"labels" json:"labels"`
+	State          string    `db:"state" json:"state"`
+	ExitCode       int       `db:"exit_code" json:"exit_code"`
+	RestartCount   int       `db:"restart_count" json:"restart_count"`
+	OOMKilled      bool      `db:"oom_killed" json:"oom_killed"`
+	Health         *Health   `db:"-" json:"health,omitempty"`
+	HealthStatus   string    `db:"health_status" json:"health_status"`
+	FailingStreak  int       `db:"health_failing_streak" json:"health_failing_streak"`
+	HealthOutput   string    `db:"health_output" json:"health_output"`
+	Down           bool      `db:"down" json:"down"`
+	LastSeen       time.Time `db:"last_seen" json:"last_seen"`
+	StateChangedAt time.Time `db:"state_changed_at" json:"state_changed_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Health — состояние Docker HEALTHCHECK, присылаемое пингером.
+type Health struct {
+	Status        string    `json:"status"`
+	FailingStreak int       `json:"failing_streak"`
+	LastOutput    string    `json:"last_output"`
+	LastExitCode  int       `json:"last_exit_code"`
+	LastCheck     time.Time `json:"last_check"`
+}
+
+// HealthUnhealthy — статус HEALTHCHECK, который бэкенд выделяет отдельно от недоступности.
+const HealthUnhealthy = "unhealthy"
+
+// StateVanished — состояние контейнера, о котором пингер перестал сообщать.
+const StateVanished = "vanished"
+
+// Labels хранится в PostgreSQL как JSONB.
+type Labels map[string]string
+
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(l)
+}
+
+func (l *Labels) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported labels type")
+	}
+	return json.Unmarshal(data, l)
+}
+
+// JSON хранится в PostgreSQL как JSONB и передаётся без изменений; NULL
+// читается как пустое значение. json.RawMessage для этого не годится:
+// database/sql не умеет сканировать в него NULL.
+type JSON []byte
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
+
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return []byte(j), nil
+}
+
+func (j *JSON) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(JSON(nil), v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return errors.New("unsupported JSON type")
+	}
+	return nil
 }
 
 type Container struct {
+	ID          int       `db:"id" json:"id"`
+	ContainerID string    `db:"container_id" json:"container_id"`
+	Identity    string    `db:"identity" json:"identity"`
+	Source      string    `db:"source" json:"source"`
+	Host        string    `db:"host" json:"host"`
+	Name        string    `db:"name" json:"name"`
+	Image       string    `db:"image" json:"image"`
+	Service     string    `db:"compose_service" json:"compose_service"`
+	State       string    `db:"state" json:"state"`
+	Health      string    `db:"health_status" json:"health_status"`
+	Down        bool      `db:"down" json:"down"`
+	Condition   string    `db:"-" json:"condition"`
+	PingTime    float64   `db:"ping_time" json:"ping_time"`
+	IPAddress   string    `db:"ip_address" json:"ip_address"`
+	Network     string    `db:"network" json:"network"`
+	Family      string    `db:"family" json:"family"`
+	Mode        string    `db:"mode" json:"mode"`
+	Details     JSON      `db:"details" json:"details,omitempty"`
+	LastPing    time.Time `db:"last_ping" json:"last_ping"`
+	Status      bool      `db:"status" json:"status"`
+	Reason      string    `db:"reason" json:"reason,omitempty"`
+}
+
+// ProbeResult — результат проверки контейнера в общем формате пингера;
+// Details содержит данные, специфичные для типа проверки.
+type ProbeResult struct {
+	ID          int       `db:"id" json:"id"`
+	ContainerID string    `db:"container_id" json:"container_id"`
+	ProbeType   string    `db:"probe_type" json:"probe_type"`
+	Target      string    `db:"target" json:"target"`
+	Status      bool      `db:"status" json:"status"`
+	Latency     float64   `db:"latency" json:"latency"`
+	Failure     string    `db:"failure" json:"failure"`
+	Error       string    `db:"error" json:"error"`
+	Details     JSON      `db:"details" json:"details,omitempty"`
+	CheckedAt   time.Time `db:"checked_at" json:"checked_at"`
+}
+
+// Итоговые состояния контейнера в Container.Condition.
+const (
+	ConditionUp              = "up"
+	ConditionUnreachable     = "unreachable"
+	ConditionNoSharedNetwork = "no_shared_network"
+	ConditionSlow            = "slow"
+	ConditionProbeImpaired   = "probe_impaired"
+	ConditionUnhealthy       = "unhealthy"
+	ConditionDown            = "down"
+)
+
+// Причины неудачной проверки: ReasonNoSharedNetwork — пингер не подключён к
+// сети контейнера и не смог его проверить, ReasonLatencyThreshold — контейнер
+// ответил медленнее, чем разрешает правило мониторинга, ReasonProbeImpaired —
+// в момент проверки у самого пингера была неисправна сеть.
+const (
+	ReasonNoSharedNetwork  = "no_shared_network"
+	ReasonLatencyThreshold = "latency_threshold"
+	ReasonProbeImpaired    = "probe_impaired"
+)
+
+// ComputeCondition различает остановленный, недоступный по сети и
+// доступный, но не прошедший HEALTHCHECK контейнер. Контейнер в сети, куда
+// пингер не подключён, не считается недоступным: его просто не проверяли.
+func (c *Container) ComputeCondition() {
+	switch {
+	case c.Down:
+		c.Condition = ConditionDown
+	case !c.Status && c.Reason == ReasonNoSharedNetwork:
+		c.Condition = ConditionNoSharedNetwork
+	case !c.Status && c.Reason == ReasonLatencyThreshold:
+		c.Condition = ConditionSlow
+	case !c.Status && c.Reason == ReasonProbeImpaired:
+		c.Condition = ConditionProbeImpaired
+	case !c.Status:
+		c.Condition = ConditionUnreachable
+	case c.Health == HealthUnhealthy:
+		c.Condition = ConditionUnhealthy
+	default:
+		c.Condition = ConditionUp
+	}
+}
+
+// ContainerStats — выборка потребления ресурсов контейнером из Docker stats API.
+// Счётчики сети и дисков накопительные с момента запуска контейнера.
+type ContainerStats struct {
+	ID            int       `db:"id" json:"id"`
+	ContainerID   string    `db:"container_id" json:"container_id"`
+	Host          string    `db:"host" json:"host"`
+	Timestamp     time.Time `db:"sampled_at" json:"timestamp"`
+	CPUPercent    float64   `db:"cpu_percent" json:"cpu_percent"`
+	OnlineCPUs    int       `db:"online_cpus" json:"online_cpus"`
+	MemoryUsage   int64     `db:"memory_usage" json:"memory_usage"`
+	MemoryLimit   int64     `db:"memory_limit" json:"memory_limit"`
+	MemoryPercent float64   `db:"memory_percent" json:"memory_percent"`
+	NetworkRx     int64     `db:"network_rx" json:"network_rx"`
+	NetworkTx     int64     `db:"network_tx" json:"network_tx"`
+	BlockRead     int64     `db:"block_read" json:"block_read"`
+	BlockWrite    int64     `db:"block_write" json:"block_write"`
+	PIDs          int64     `db:"pids" json:"pids"`
+}
+
+// PingRecord — одна запись истории проверок контейнера.
+type PingRecord struct {
 	ID        int       `db:"id" json:"id"`
-	PingTime  float64   `db:"ping_time" json:"ping_time"`
 	IPAddress string    `db:"ip_address" json:"ip_address"`
+	Network   string    `db:"network" json:"network"`
+	Family    string    `db:"family" json:"family"`
+	Mode      string    `db:"mode" json:"mode"`
+	Details   JSON      `db:"details" json:"details,omitempty"`
 	LastPing  time.Time `db:"last_ping" json:"last_ping"`
+	PingTime  float64   `db:"ping_time" json:"ping_time"`
 	Status    bool      `db:"status" json:"status"`
+	Reason    string    `db:"reason" json:"reason,omitempty"`
+}
+
+// ContainerEvent — событие жизненного цикла контейнера из Docker.
+// ExitCode заполняется для die, Signal — для kill, HealthStatus — для health_status.
+type ContainerEvent struct {
+	ID           int       `db:"id" json:"id"`
+	ContainerID  string    `db:"container_id" json:"container_id"`
+	Host         string    `db:"host" json:"host"`
+	Action       string    `db:"action" json:"action"`
+	Name         string    `db:"name" json:"name,omitempty"`
+	Image        string    `db:"image" json:"image,omitempty"`
+	Network      string    `db:"network" json:"network,omitempty"`
+	ExitCode     *int      `db:"exit_code" json:"exit_code,omitempty"`
+	Signal       string    `db:"signal" json:"signal,omitempty"`
+	HealthStatus string    `db:"health_status" json:"health_status,omitempty"`
+	Time         time.Time `db:"event_time" json:"time"`
+}
+
+// Виды записей ленты событий.
+const (
+	TimelineEvent       = "event"
+	TimelinePingFailure = "ping_failure"
+)
+
+// TimelineEntry — запись общей ленты: событие Docker или неудачная проверка.
+// Для событий Detail — действие, для неудачных проверок — адрес.
+type TimelineEntry struct {
+	Time        time.Time `db:"time" json:"time"`
+	Kind        string    `db:"kind" json:"kind"`
+	ContainerID string    `db:"container_id" json:"container_id"`
+	Name        string    `db:"name" json:"name"`
+	Detail      string    `db:"detail" json:"detail"`
+	ExitCode    *int      `db:"exit_code" json:"exit_code,omitempty"`
+}
+
+// AddressRecord — период, в течение которого логический контейнер имел
+// адрес в сети. Новая запись появляется при смене адреса или пересоздании контейнера.
+type AddressRecord struct {
+	ID          int       `db:"id" json:"id"`
+	Identity    string    `db:"identity" json:"identity"`
+	ContainerID string    `db:"container_id" json:"container_id"`
+	Network     string    `db:"network" json:"network"`
+	Family      string    `db:"family" json:"family"`
+	IPAddress   string    `db:"ip_address" json:"ip_address"`
+	FirstSeen   time.Time `db:"first_seen" json:"first_seen"`
+	LastSeen    time.Time `db:"last_seen" json:"last_seen"`
+}
+
+// HostSummary — сводка по контейнерам одного демона Docker.
+type HostSummary struct {
+	Host      string `db:"host" json:"host"`
+	Total     int    `db:"total" json:"total"`
+	Running   int    `db:"running" json:"running"`
+	Down      int    `db:"down" json:"down"`
+	Unhealthy int    `db:"unhealthy" json:"unhealthy"`
+}
+
+// ServiceHealth — доступность сервиса Swarm: Reachable из Desired задач
+// ответили на последнюю проверку.
+type ServiceHealth struct {
+	ID          int       `db:"id" json:"id"`
+	ServiceID   string    `db:"service_id" json:"service_id"`
+	ServiceName string    `db:"service_name" json:"service_name"`
+	Desired     int       `db:"desired" json:"desired"`
+	Running     int       `db:"running" json:"running"`
+	Reachable   int       `db:"reachable" json:"reachable"`
+	Status      bool      `db:"status" json:"status"`
+	Timestamp   time.Time `db:"checked_at" json:"timestamp"`
+}
+
+// MonitorRule — правило мониторинга, которое пингер применяет к подходящим целям.
+// Container сравнивается с именем, логической идентичностью или ID цели и может
+// быть шаблоном path.Match; Labels требует совпадения меток, пустое значение —
+// только наличия метки. Из подходящих правил действует первое по Priority.
+// Нулевые Interval и MaxLatencyMs означают значения пингера по умолчанию,
+// Options переопределяет метки проверок цели, например monitor.http.path.
+type MonitorRule struct {
+	ID           int       `db:"id" json:"id"`
+	Name         string    `db:"name" json:"name" validate:"required,max=255"`
+	Priority     int       `db:"priority" json:"priority"`
+	Container    string    `db:"container" json:"container" validate:"max=255"`
+	Labels       Labels    `db:"labels" json:"labels"`
+	Exclude      bool      `db:"exclude" json:"exclude"`
+	Interval     int       `db:"interval_seconds" json:"interval_seconds" validate:"gte=0"`
+	Probes       Strings   `db:"probes" json:"probes"`
+	Options      Labels    `db:"options" json:"options"`
+	MaxLatencyMs int       `db:"max_latency_ms" json:"max_latency_ms" validate:"gte=0"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// MonitorConfig — конфигурация мониторинга, которую забирает пингер.
+// Version увеличивается при каждом изменении правил.
+type MonitorConfig struct {
+	Version int64         `json:"version"`
+	Rules   []MonitorRule `json:"rules"`
+}
+
+// ErrRuleNotFound возвращается при изменении несуществующего правила.
+var ErrRuleNotFound = errors.New("monitor rule not found")
+
+// ErrRuleInterval возвращается для правила, интервал которого слишком велик:
+// цель успела бы попасть в пропавшие между двумя проверками.
+var ErrRuleInterval = errors.New("monitor rule interval exceeds half of the container grace period")
+
+// Strings хранится в PostgreSQL как JSONB-массив.
+type Strings []string
+
+func (s Strings) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *Strings) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported strings type")
+	}
+	return json.Unmarshal(data, s)
+}
+
+// ProbeRequest — запрос внеочередной проверки контейнера у пингера.
+type ProbeRequest struct {
+	ContainerID string `json:"container_id"`
+}
+
+// ProbeResponse — ответ пингера на ProbeRequest: свежие результаты проверки
+// по каждому адресу контейнера или ошибка.
+type ProbeResponse struct {
+	Results []PingResult `json:"results"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// ProbeErrorNotFound — пингер не знает контейнер из запроса.
+const ProbeErrorNotFound = "container_not_found"
+
+// Операции сетевой диагностики.
+const (
+	DiagnosticTraceroute = "traceroute"
+	DiagnosticMTU        = "mtu"
+	DiagnosticDNS        = "dns"
+)
+
+// DiagnosticRequest — запрос сетевой диагностики контейнера у пингера.
+// Пустой Operations — все операции.
+type DiagnosticRequest struct {
+	ContainerID string   `json:"container_id"`
+	Operations  []string `json:"operations,omitempty"`
+}
+
+// DiagnosticResponse — ответ пингера на DiagnosticRequest.
+type DiagnosticResponse struct {
+	ContainerID string       `json:"container_id"`
+	Identity    string       `json:"identity"`
+	Results     []Diagnostic `json:"results"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Diagnostic — результат одной операции диагностики для одного адреса или
+// имени контейнера. Details — маршрут, MTU пути или ответ DNS.
+type Diagnostic struct {
+	ID          int       `db:"id" json:"id"`
+	ContainerID string    `db:"container_id" json:"container_id"`
+	Identity    string    `db:"identity" json:"identity"`
+	Operation   string    `db:"operation" json:"operation"`
+	Target      string    `db:"target" json:"target"`
+	Network     string    `db:"network" json:"network"`
+	Status      bool      `db:"status" json:"status"`
+	Error       string    `db:"error" json:"error,omitempty"`
+	Details     JSON      `db:"details" json:"details,omitempty"`
+	Timestamp   time.Time `db:"created_at" json:"timestamp"`
 }
@@ -2,10 +2,15 @@ package delivery
 
 import (
 	"backend/domain"
+	"crypto/subtle"
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"net/http"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"backend/service"
 	"github.com/go-playground/validator/v10"
@@ -72,6 +77,355 @@ func (h *HTTPHandler) GetContainers(c echo.Context) error {
 	return c.JSON(http.StatusOK, containers)
 }
 
+func (h *HTTPHandler) GetInventory(c echo.Context) error {
+	ctx := c.Request().Context()
+	inventory, err := h.backendService.GetInventory(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch inventory"})
+	}
+	return c.JSON(http.StatusOK, inventory)
+}
+
+func (h *HTTPHandler) GetProbeResults(c echo.Context) error {
+	ctx := c.Request().Context()
+	limit, err := queryLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+
+	results, err := h.backendService.GetProbeResults(ctx, c.Param("id"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch probe results"})
+	}
+	return c.JSON(http.StatusOK, results)
+}
+
+// probeTimeout ограничивает ожидание ответа пингера на внеочередную проверку.
+const probeTimeout = 15 * time.Second
+
+func (h *HTTPHandler) ProbeContainer(c echo.Context) error {
+	ctx := c.Request().Context()
+	results, err := h.backendService.ProbeNow(ctx, c.Param("id"), probeTimeout)
+	switch {
+	case errors.Is(err, service.ErrContainerNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Container is not monitored"})
+	case errors.Is(err, service.ErrProbeTimeout):
+		return c.JSON(http.StatusGatewayTimeout, map[string]string{"error": "Pinger did not respond in time"})
+	case err != nil:
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to probe container"})
+	}
+	return c.JSON(http.StatusOK, results)
+}
+
+// diagnosticTimeout ограничивает ожидание диагностики: traceroute и поиск MTU небыстрые.
+const diagnosticTimeout = 60 * time.Second
+
+type diagnosticRequest struct {
+	Operations []string `json:"operations"`
+}
+
+func (h *HTTPHandler) RunDiagnostics(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req diagnosticRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	for _, operation := range req.Operations {
+		switch operation {
+		case domain.DiagnosticTraceroute, domain.DiagnosticMTU, domain.DiagnosticDNS:
+		default:
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown operation " + operation})
+		}
+	}
+
+	diagnostics, err := h.backendService.RunDiagnostics(ctx, c.Param("id"), req.Operations, diagnosticTimeout)
+	switch {
+	case errors.Is(err, service.ErrContainerNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Container is not monitored"})
+	case errors.Is(err, service.ErrProbeTimeout):
+		return c.JSON(http.StatusGatewayTimeout, map[string]string{"error": "Pinger did not respond in time"})
+	case err != nil:
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to run diagnostics"})
+	}
+	return c.JSON(http.StatusOK, diagnostics)
+}
+
+func (h *HTTPHandler) GetDiagnostics(c echo.Context) error {
+	ctx := c.Request().Context()
+	limit, err := queryLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+	since, err := querySince(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid since"})
+	}
+
+	diagnostics, err := h.backendService.GetDiagnostics(ctx, c.Param("id"), since, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch diagnostics"})
+	}
+	return c.JSON(http.StatusOK, diagnostics)
+}
+
+func (h *HTTPHandler) GetPingHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+	limit, err := queryLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+	since, err := querySince(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid since"})
+	}
+
+	history, err := h.backendService.GetPingHistory(ctx, c.Param("id"), since, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch ping history"})
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
+func (h *HTTPHandler) GetContainerStats(c echo.Context) error {
+	ctx := c.Request().Context()
+	limit, err := queryLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+	since, err := querySince(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid since"})
+	}
+
+	stats, err := h.backendService.GetContainerStats(ctx, c.Param("id"), since, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch container stats"})
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (h *HTTPHandler) GetContainerEvents(c echo.Context) error {
+	ctx := c.Request().Context()
+	limit, err := queryLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+	since, err := querySince(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid since"})
+	}
+
+	events, err := h.backendService.GetContainerEvents(ctx, c.Param("id"), since, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch container events"})
+	}
+	return c.JSON(http.StatusOK, events)
+}
+
+func (h *HTTPHandler) GetTimeline(c echo.Context) error {
+	ctx := c.Request().Context()
+	limit, err := queryLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+	since, err := querySince(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid since"})
+	}
+
+	timeline, err := h.backendService.GetTimeline(ctx, since, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch timeline"})
+	}
+	return c.JSON(http.StatusOK, timeline)
+}
+
+func (h *HTTPHandler) GetAddressHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+	limit, err := queryLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+
+	history, err := h.backendService.GetAddressHistory(ctx, c.Param("id"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch address history"})
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
+func (h *HTTPHandler) GetHosts(c echo.Context) error {
+	ctx := c.Request().Context()
+	hosts, err := h.backendService.GetHosts(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch hosts"})
+	}
+	return c.JSON(http.StatusOK, hosts)
+}
+
+func (h *HTTPHandler) GetServices(c echo.Context) error {
+	ctx := c.Request().Context()
+	services, err := h.backendService.GetServices(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch services"})
+	}
+	return c.JSON(http.StatusOK, services)
+}
+
+func (h *HTTPHandler) GetServiceHealth(c echo.Context) error {
+	ctx := c.Request().Context()
+	limit, err := queryLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+	since, err := querySince(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid since"})
+	}
+
+	history, err := h.backendService.GetServiceHealth(ctx, c.Param("id"), since, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch service health"})
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
+func (h *HTTPHandler) GetMonitorRules(c echo.Context) error {
+	ctx := c.Request().Context()
+	rules, err := h.backendService.GetMonitorRules(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch monitor rules"})
+	}
+	return c.JSON(http.StatusOK, rules)
+}
+
+func (h *HTTPHandler) CreateMonitorRule(c echo.Context) error {
+	ctx := c.Request().Context()
+	rule, err := bindMonitorRule(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	saved, err := h.backendService.CreateMonitorRule(ctx, rule)
+	if errors.Is(err, domain.ErrRuleInterval) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create monitor rule"})
+	}
+	return c.JSON(http.StatusCreated, saved)
+}
+
+func (h *HTTPHandler) UpdateMonitorRule(c echo.Context) error {
+	ctx := c.Request().Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid rule id"})
+	}
+	rule, err := bindMonitorRule(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	rule.ID = id
+
+	saved, err := h.backendService.UpdateMonitorRule(ctx, rule)
+	if errors.Is(err, domain.ErrRuleInterval) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if errors.Is(err, domain.ErrRuleNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Monitor rule not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update monitor rule"})
+	}
+	return c.JSON(http.StatusOK, saved)
+}
+
+func (h *HTTPHandler) DeleteMonitorRule(c echo.Context) error {
+	ctx := c.Request().Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid rule id"})
+	}
+
+	err = h.backendService.DeleteMonitorRule(ctx, id)
+	if errors.Is(err, domain.ErrRuleNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Monitor rule not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete monitor rule"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetMonitorConfig отдаёт пингеру правила мониторинга.
+func (h *HTTPHandler) GetMonitorConfig(c echo.Context) error {
+	ctx := c.Request().Context()
+	config, err := h.backendService.GetMonitorConfig(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch monitor config"})
+	}
+	return c.JSON(http.StatusOK, config)
+}
+
+// bindMonitorRule читает и проверяет правило из тела запроса.
+func bindMonitorRule(c echo.Context) (domain.MonitorRule, error) {
+	var rule domain.MonitorRule
+	if err := c.Bind(&rule); err != nil {
+		return rule, errors.New("Invalid request")
+	}
+	if err := validator.New().Struct(rule); err != nil {
+		return rule, err
+	}
+	if _, err := path.Match(rule.Container, ""); err != nil {
+		return rule, errors.New("Invalid container pattern")
+	}
+	for _, name := range rule.Probes {
+		if strings.TrimSpace(name) == "" {
+			return rule, errors.New("Probe name must not be empty")
+		}
+	}
+	return rule, nil
+}
+
+// queryLimit читает параметр limit; по умолчанию 100.
+func queryLimit(c echo.Context) (int, error) {
+	value := c.QueryParam("limit")
+	if value == "" {
+		return 100, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid limit")
+	}
+	return n, nil
+}
+
+// querySince читает параметр since в формате RFC 3339; по умолчанию — без ограничения.
+func querySince(c echo.Context) (time.Time, error) {
+	value := c.QueryParam("since")
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// PingerAuthMiddleware пропускает запросы пингера с токеном PINGER_TOKEN.
+// Без заданного токена маршрут закрыт.
+func (h *HTTPHandler) PingerAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := os.Getenv("PINGER_TOKEN")
+		if token == "" {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Pinger token is not configured"})
+		}
+		header := c.Request().Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+token)) != 1 {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid pinger token"})
+		}
+		return next(c)
+	}
+}
+
 func (h *HTTPHandler) AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		authHeader := c.Request().Header.Get("Authorization")
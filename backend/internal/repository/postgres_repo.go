@@ -8,11 +8,37 @@ import (
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"log"
+	"time"
 )
 
 type PostgresRepository interface {
 	SavePingResult(ctx context.Context, result domain.PingResult) error
-	GetAllContainers(ctx context.Context) ([]domain.Container, error)
+	GetAllContainers(ctx context.Context, recent time.Duration) ([]domain.Container, error)
+	SaveContainerMeta(ctx context.Context, meta domain.ContainerMeta, seenAt time.Time) error
+	MarkStaleContainers(ctx context.Context, grace time.Duration) (int64, error)
+	GetInventory(ctx context.Context) ([]domain.ContainerMeta, error)
+	GetContainerMeta(ctx context.Context, containerID string) (*domain.ContainerMeta, error)
+	SaveProbeResults(ctx context.Context, results []domain.ProbeResult) error
+	GetProbeResults(ctx context.Context, containerID string, limit int) ([]domain.ProbeResult, error)
+	GetPingHistory(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.PingRecord, error)
+	SaveContainerStats(ctx context.Context, stats domain.ContainerStats) error
+	GetContainerStats(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.ContainerStats, error)
+	SaveContainerEvent(ctx context.Context, event domain.ContainerEvent) error
+	GetContainerEvents(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.ContainerEvent, error)
+	GetTimeline(ctx context.Context, since time.Time, limit int) ([]domain.TimelineEntry, error)
+	SaveAddress(ctx context.Context, record domain.AddressRecord) error
+	GetAddressHistory(ctx context.Context, containerID string, limit int) ([]domain.AddressRecord, error)
+	GetHosts(ctx context.Context) ([]domain.HostSummary, error)
+	SaveServiceHealth(ctx context.Context, health domain.ServiceHealth) error
+	GetServices(ctx context.Context) ([]domain.ServiceHealth, error)
+	GetServiceHealth(ctx context.Context, serviceID string, since time.Time, limit int) ([]domain.ServiceHealth, error)
+	GetMonitorRules(ctx context.Context) ([]domain.MonitorRule, error)
+	GetMonitorConfig(ctx context.Context) (domain.MonitorConfig, error)
+	CreateMonitorRule(ctx context.Context, rule domain.MonitorRule) (domain.MonitorRule, error)
+	UpdateMonitorRule(ctx context.Context, rule domain.MonitorRule) (domain.MonitorRule, error)
+	DeleteMonitorRule(ctx context.Context, id int) error
+	SaveDiagnostics(ctx context.Context, diagnostics []domain.Diagnostic) error
+	GetDiagnostics(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.Diagnostic, error)
 }
 
 type postgresRepository struct {
@@ -25,10 +51,28 @@ func NewPostgresRepository(db *sqlx.DB) PostgresRepository {
 
 func (r *postgresRepository) SavePingResult(ctx context.Context, result domain.PingResult) error {
 	query := `
-        INSERT INTO containers (ip_address, last_ping,ping_time, status)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO containers (container_id, identity, host, ip_address, network, family, mode, details, last_ping, ping_time, status, reason)
+        VALUES ($1, $2, $11, $3, $4, $5, $6, $7, $8, $9, $10, $12)
     `
-	_, err := r.db.ExecContext(ctx, query, result.IP, result.LastSuccess, result.PingTime, result.Status)
+	// Время измерения берём из сообщения, чтобы отложенные в outbox результаты не смещались
+	lastPing := result.Timestamp
+	if lastPing.IsZero() {
+		lastPing = result.LastSuccess
+	}
+	mode := result.Mode
+	if mode == "" {
+		mode = "external"
+	}
+	var details interface{}
+	if len(result.Netns) > 0 {
+		details = []byte(result.Netns)
+	}
+	var identity string
+	if result.Container != nil {
+		identity = result.Container.Identity
+	}
+	_, err := r.db.ExecContext(ctx, query, result.ContainerID, identity, result.IP, result.Network, result.Family,
+		mode, details, lastPing, result.PingTime, result.Status, result.Host, result.Reason)
 	if err != nil {
 		log.Printf("Failed to save ping result: %v", err)
 		return err
@@ -36,13 +80,26 @@ func (r *postgresRepository) SavePingResult(ctx context.Context, result domain.P
 	return nil
 }
 
-func (r *postgresRepository) GetAllContainers(ctx context.Context) ([]domain.Container, error) {
+func (r *postgresRepository) GetAllContainers(ctx context.Context, recent time.Duration) ([]domain.Container, error) {
+	// Последний результат на каждый логический контейнер, сеть и семейство адресов;
+	// строки без container_id (старые записи) группируются по адресу. Сбои при
+	// неисправной сети пингера не перекрывают предыдущий результат, пока тот не
+	// старше recent; дальше виден сам результат probe_impaired
 	query := `
-        SELECT id, ip_address, last_ping, status, ping_time
-        FROM containers
+        SELECT DISTINCT ON (identity, c.network, c.family, c.mode)
+               c.id, c.container_id, c.ip_address, c.network, c.family, c.mode, c.details, c.last_ping, c.status, c.ping_time, c.reason,
+               COALESCE(NULLIF(c.identity, ''), NULLIF(c.container_id, ''), c.ip_address) AS identity,
+               COALESCE(i.source, '') AS source, COALESCE(NULLIF(c.host, ''), i.host, '') AS host, COALESCE(i.name, '') AS name, COALESCE(i.image, '') AS image,
+               COALESCE(i.compose_service, '') AS compose_service, COALESCE(i.state, '') AS state,
+               COALESCE(i.health_status, '') AS health_status, COALESCE(i.down, FALSE) AS down
+        FROM containers c
+        LEFT JOIN container_inventory i ON i.container_id = c.container_id
+        ORDER BY identity, c.network, c.family, c.mode,
+                 c.reason = 'probe_impaired' OR c.last_ping < NOW() - $1 * INTERVAL '1 second',
+                 c.last_ping DESC
     `
 	var containers []domain.Container
-	err := r.db.SelectContext(ctx, &containers, query)
+	err := r.db.SelectContext(ctx, &containers, query, recent.Seconds())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -52,3 +109,509 @@ func (r *postgresRepository) GetAllContainers(ctx context.Context) ([]domain.Con
 	}
 	return containers, nil
 }
+
+func (r *postgresRepository) SaveContainerMeta(ctx context.Context, meta domain.ContainerMeta, seenAt time.Time) error {
+	query := `
+        INSERT INTO container_inventory (container_id, identity, source, host, name, image, image_digest, compose_project, compose_service,
+                                         labels, state, exit_code, restart_count, oom_killed,
+                                         health_status, health_failing_streak, health_output, down,
+                                         last_seen, state_changed_at, updated_at)
+        VALUES ($1, $16, COALESCE(NULLIF($17, ''), 'docker'), $18, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $13, $14, $15, FALSE, $12, $12, NOW())
+        ON CONFLICT (container_id) DO UPDATE SET
+            identity = EXCLUDED.identity,
+            source = EXCLUDED.source,
+            host = EXCLUDED.host,
+            name = EXCLUDED.name,
+            image = EXCLUDED.image,
+            image_digest = EXCLUDED.image_digest,
+            compose_project = EXCLUDED.compose_project,
+            compose_service = EXCLUDED.compose_service,
+            labels = EXCLUDED.labels,
+            state_changed_at = CASE WHEN container_inventory.state <> EXCLUDED.state
+                                    THEN EXCLUDED.state_changed_at ELSE container_inventory.state_changed_at END,
+            state = EXCLUDED.state,
+            exit_code = EXCLUDED.exit_code,
+            restart_count = EXCLUDED.restart_count,
+            oom_killed = EXCLUDED.oom_killed,
+            health_status = EXCLUDED.health_status,
+            health_failing_streak = EXCLUDED.health_failing_streak,
+            health_output = EXCLUDED.health_output,
+            down = CASE WHEN EXCLUDED.state = 'running' THEN FALSE ELSE container_inventory.down END,
+            last_seen = GREATEST(container_inventory.last_seen, EXCLUDED.last_seen),
+            updated_at = NOW()
+    `
+	if meta.Health != nil {
+		meta.HealthStatus = meta.Health.Status
+		meta.FailingStreak = meta.Health.FailingStreak
+		meta.HealthOutput = meta.Health.LastOutput
+	}
+	_, err := r.db.ExecContext(ctx, query, meta.ID, meta.Name, meta.Image, meta.ImageDigest,
+		meta.ComposeProject, meta.ComposeService, meta.Labels, meta.State,
+		meta.ExitCode, meta.RestartCount, meta.OOMKilled, seenAt,
+		meta.HealthStatus, meta.FailingStreak, meta.HealthOutput, meta.Identity, meta.Source, meta.Host)
+	if err != nil {
+		log.Printf("Failed to save container metadata: %v", err)
+		return err
+	}
+	return nil
+}
+
+// MarkStaleContainers помечает недоступными контейнеры, которые не работают
+// или о которых не приходило сообщений дольше grace.
+func (r *postgresRepository) MarkStaleContainers(ctx context.Context, grace time.Duration) (int64, error) {
+	query := `
+        UPDATE container_inventory SET
+            down = TRUE,
+            state = CASE WHEN last_seen < NOW() - $1 * INTERVAL '1 second' THEN $2 ELSE state END,
+            updated_at = NOW()
+        WHERE down = FALSE AND (
+            last_seen < NOW() - $1 * INTERVAL '1 second'
+            OR (state <> 'running' AND state_changed_at < NOW() - $1 * INTERVAL '1 second')
+        )
+    `
+	res, err := r.db.ExecContext(ctx, query, grace.Seconds(), domain.StateVanished)
+	if err != nil {
+		log.Printf("Failed to mark stale containers: %v", err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *postgresRepository) GetInventory(ctx context.Context) ([]domain.ContainerMeta, error) {
+	query := `
+        SELECT container_id, identity, source, host, name, image, image_digest, compose_project, compose_service, labels, state,
+               exit_code, restart_count, oom_killed, health_status, health_failing_streak, health_output,
+               down, last_seen, state_changed_at, updated_at
+        FROM container_inventory
+        ORDER BY name
+    `
+	var inventory []domain.ContainerMeta
+	err := r.db.SelectContext(ctx, &inventory, query)
+	if err != nil {
+		log.Printf("Failed to fetch container inventory: %v", err)
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// GetContainerMeta возвращает метаданные контейнера или nil, если его нет в инвентаре.
+func (r *postgresRepository) GetContainerMeta(ctx context.Context, containerID string) (*domain.ContainerMeta, error) {
+	query := `
+        SELECT container_id, identity, source, host, name, image, image_digest, compose_project, compose_service, labels, state,
+               exit_code, restart_count, oom_killed, health_status, health_failing_streak, health_output,
+               down, last_seen, state_changed_at, updated_at
+        FROM container_inventory
+        WHERE container_id = $1
+    `
+	var meta domain.ContainerMeta
+	err := r.db.GetContext(ctx, &meta, query, containerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Printf("Failed to fetch container metadata: %v", err)
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (r *postgresRepository) SaveProbeResults(ctx context.Context, results []domain.ProbeResult) error {
+	query := `
+        INSERT INTO probe_results (container_id, probe_type, target, status, latency, failure, error, details, checked_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `
+	for _, result := range results {
+		var details interface{}
+		if len(result.Details) > 0 {
+			details = []byte(result.Details)
+		}
+		_, err := r.db.ExecContext(ctx, query, result.ContainerID, result.ProbeType, result.Target, result.Status,
+			result.Latency, result.Failure, result.Error, details, result.CheckedAt)
+		if err != nil {
+			log.Printf("Failed to save probe result: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetProbeResults(ctx context.Context, containerID string, limit int) ([]domain.ProbeResult, error) {
+	query := `
+        SELECT id, container_id, probe_type, target, status, latency, failure, error, details, checked_at
+        FROM probe_results
+        WHERE container_id = $1
+        ORDER BY checked_at DESC
+        LIMIT $2
+    `
+	var results []domain.ProbeResult
+	err := r.db.SelectContext(ctx, &results, query, containerID, limit)
+	if err != nil {
+		log.Printf("Failed to fetch probe results: %v", err)
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *postgresRepository) GetPingHistory(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.PingRecord, error) {
+	query := `
+        SELECT id, ip_address, network, family, mode, details, last_ping, ping_time, status, reason
+        FROM containers
+        WHERE container_id = $1 AND last_ping >= $2
+        ORDER BY last_ping DESC
+        LIMIT $3
+    `
+	var records []domain.PingRecord
+	err := r.db.SelectContext(ctx, &records, query, containerID, since, limit)
+	if err != nil {
+		log.Printf("Failed to fetch ping history: %v", err)
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *postgresRepository) SaveContainerStats(ctx context.Context, stats domain.ContainerStats) error {
+	query := `
+        INSERT INTO container_stats (container_id, host, sampled_at, cpu_percent, online_cpus, memory_usage, memory_limit,
+                                     memory_percent, network_rx, network_tx, block_read, block_write, pids)
+        VALUES (:container_id, :host, :sampled_at, :cpu_percent, :online_cpus, :memory_usage, :memory_limit,
+                :memory_percent, :network_rx, :network_tx, :block_read, :block_write, :pids)
+    `
+	if stats.Timestamp.IsZero() {
+		stats.Timestamp = time.Now()
+	}
+	_, err := r.db.NamedExecContext(ctx, query, stats)
+	if err != nil {
+		log.Printf("Failed to save container stats: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetContainerStats(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.ContainerStats, error) {
+	query := `
+        SELECT id, container_id, host, sampled_at, cpu_percent, online_cpus, memory_usage, memory_limit,
+               memory_percent, network_rx, network_tx, block_read, block_write, pids
+        FROM container_stats
+        WHERE container_id = $1 AND sampled_at >= $2
+        ORDER BY sampled_at DESC
+        LIMIT $3
+    `
+	var stats []domain.ContainerStats
+	err := r.db.SelectContext(ctx, &stats, query, containerID, since, limit)
+	if err != nil {
+		log.Printf("Failed to fetch container stats: %v", err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (r *postgresRepository) SaveContainerEvent(ctx context.Context, event domain.ContainerEvent) error {
+	query := `
+        INSERT INTO container_events (container_id, host, action, name, image, network, exit_code, signal, health_status, event_time)
+        VALUES (:container_id, :host, :action, :name, :image, :network, :exit_code, :signal, :health_status, :event_time)
+    `
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	_, err := r.db.NamedExecContext(ctx, query, event)
+	if err != nil {
+		log.Printf("Failed to save container event: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetContainerEvents(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.ContainerEvent, error) {
+	query := `
+        SELECT id, container_id, host, action, name, image, network, exit_code, signal, health_status, event_time
+        FROM container_events
+        WHERE container_id = $1 AND event_time >= $2
+        ORDER BY event_time DESC
+        LIMIT $3
+    `
+	var events []domain.ContainerEvent
+	err := r.db.SelectContext(ctx, &events, query, containerID, since, limit)
+	if err != nil {
+		log.Printf("Failed to fetch container events: %v", err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetTimeline объединяет события Docker и неудачные проверки всех контейнеров
+// в одну ленту, чтобы рестарты можно было сопоставить с недоступностью.
+func (r *postgresRepository) GetTimeline(ctx context.Context, since time.Time, limit int) ([]domain.TimelineEntry, error) {
+	query := `
+        SELECT time, kind, container_id, name, detail, exit_code FROM (
+            SELECT e.event_time AS time, 'event' AS kind, e.container_id,
+                   COALESCE(NULLIF(i.name, ''), e.name) AS name, e.action AS detail, e.exit_code
+            FROM container_events e
+            LEFT JOIN container_inventory i ON i.container_id = e.container_id
+            WHERE e.event_time >= $1
+            UNION ALL
+            SELECT c.last_ping AS time, 'ping_failure' AS kind, c.container_id,
+                   COALESCE(i.name, '') AS name, c.ip_address AS detail, NULL AS exit_code
+            FROM containers c
+            LEFT JOIN container_inventory i ON i.container_id = c.container_id
+            WHERE c.last_ping >= $1 AND NOT c.status AND c.ip_address <> '' AND c.reason NOT IN ('no_shared_network', 'probe_impaired')
+        ) timeline
+        ORDER BY time DESC
+        LIMIT $2
+    `
+	var entries []domain.TimelineEntry
+	err := r.db.SelectContext(ctx, &entries, query, since, limit)
+	if err != nil {
+		log.Printf("Failed to fetch timeline: %v", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SaveAddress продлевает текущую запись истории адресов логического контейнера
+// или начинает новую, если сменился адрес или сам контейнер.
+func (r *postgresRepository) SaveAddress(ctx context.Context, record domain.AddressRecord) error {
+	query := `
+        WITH latest AS (
+            SELECT id, container_id, ip_address
+            FROM address_history
+            WHERE identity = $1 AND network = $3 AND family = $4
+            ORDER BY last_seen DESC
+            LIMIT 1
+        ), touched AS (
+            UPDATE address_history h SET last_seen = GREATEST(h.last_seen, $6)
+            FROM latest
+            WHERE h.id = latest.id AND latest.container_id = $2 AND latest.ip_address = $5
+            RETURNING h.id
+        )
+        INSERT INTO address_history (identity, container_id, network, family, ip_address, first_seen, last_seen)
+        SELECT $1, $2, $3, $4, $5, $6, $6
+        WHERE NOT EXISTS (SELECT 1 FROM touched)
+    `
+	_, err := r.db.ExecContext(ctx, query, record.Identity, record.ContainerID, record.Network, record.Family,
+		record.IPAddress, record.LastSeen)
+	if err != nil {
+		log.Printf("Failed to save address history: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetAddressHistory возвращает историю адресов логического контейнера,
+// к которому относится containerID, включая его предыдущие экземпляры.
+func (r *postgresRepository) GetAddressHistory(ctx context.Context, containerID string, limit int) ([]domain.AddressRecord, error) {
+	query := `
+        SELECT h.id, h.identity, h.container_id, h.network, h.family, h.ip_address, h.first_seen, h.last_seen
+        FROM address_history h
+        JOIN container_inventory i ON i.identity = h.identity
+        WHERE i.container_id = $1
+        ORDER BY h.last_seen DESC
+        LIMIT $2
+    `
+	var history []domain.AddressRecord
+	err := r.db.SelectContext(ctx, &history, query, containerID, limit)
+	if err != nil {
+		log.Printf("Failed to fetch address history: %v", err)
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *postgresRepository) GetHosts(ctx context.Context) ([]domain.HostSummary, error) {
+	query := `
+        SELECT host,
+               COUNT(*) AS total,
+               COUNT(*) FILTER (WHERE state = 'running') AS running,
+               COUNT(*) FILTER (WHERE down) AS down,
+               COUNT(*) FILTER (WHERE health_status = $1) AS unhealthy
+        FROM container_inventory
+        GROUP BY host
+        ORDER BY host
+    `
+	var hosts []domain.HostSummary
+	err := r.db.SelectContext(ctx, &hosts, query, domain.HealthUnhealthy)
+	if err != nil {
+		log.Printf("Failed to fetch hosts: %v", err)
+		return nil, err
+	}
+	return hosts, nil
+}
+
+func (r *postgresRepository) SaveServiceHealth(ctx context.Context, health domain.ServiceHealth) error {
+	query := `
+        INSERT INTO service_health (service_id, service_name, desired, running, reachable, status, checked_at)
+        VALUES (:service_id, :service_name, :desired, :running, :reachable, :status, :checked_at)
+    `
+	if health.Timestamp.IsZero() {
+		health.Timestamp = time.Now()
+	}
+	_, err := r.db.NamedExecContext(ctx, query, health)
+	if err != nil {
+		log.Printf("Failed to save service health: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetServices возвращает последнюю сводку по каждому сервису Swarm.
+func (r *postgresRepository) GetServices(ctx context.Context) ([]domain.ServiceHealth, error) {
+	query := `
+        SELECT DISTINCT ON (service_id)
+               id, service_id, service_name, desired, running, reachable, status, checked_at
+        FROM service_health
+        ORDER BY service_id, checked_at DESC
+    `
+	var services []domain.ServiceHealth
+	err := r.db.SelectContext(ctx, &services, query)
+	if err != nil {
+		log.Printf("Failed to fetch services: %v", err)
+		return nil, err
+	}
+	return services, nil
+}
+
+func (r *postgresRepository) GetServiceHealth(ctx context.Context, serviceID string, since time.Time, limit int) ([]domain.ServiceHealth, error) {
+	query := `
+        SELECT id, service_id, service_name, desired, running, reachable, status, checked_at
+        FROM service_health
+        WHERE service_id = $1 AND checked_at >= $2
+        ORDER BY checked_at DESC
+        LIMIT $3
+    `
+	var history []domain.ServiceHealth
+	err := r.db.SelectContext(ctx, &history, query, serviceID, since, limit)
+	if err != nil {
+		log.Printf("Failed to fetch service health: %v", err)
+		return nil, err
+	}
+	return history, nil
+}
+
+const monitorRuleColumns = `id, name, priority, container, labels, exclude, interval_seconds, probes, options, max_latency_ms, updated_at`
+
+func (r *postgresRepository) GetMonitorRules(ctx context.Context) ([]domain.MonitorRule, error) {
+	query := `SELECT ` + monitorRuleColumns + ` FROM monitor_rules ORDER BY priority, id`
+	rules := []domain.MonitorRule{}
+	err := r.db.SelectContext(ctx, &rules, query)
+	if err != nil {
+		log.Printf("Failed to fetch monitor rules: %v", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetMonitorConfig читает правила и версию конфигурации одним снимком.
+func (r *postgresRepository) GetMonitorConfig(ctx context.Context) (domain.MonitorConfig, error) {
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return domain.MonitorConfig{}, err
+	}
+	defer tx.Rollback()
+
+	var config domain.MonitorConfig
+	err = tx.GetContext(ctx, &config.Version, `SELECT COALESCE((SELECT version FROM monitor_config), 0)`)
+	if err != nil {
+		log.Printf("Failed to fetch monitor config version: %v", err)
+		return domain.MonitorConfig{}, err
+	}
+	config.Rules = []domain.MonitorRule{}
+	err = tx.SelectContext(ctx, &config.Rules, `SELECT `+monitorRuleColumns+` FROM monitor_rules ORDER BY priority, id`)
+	if err != nil {
+		log.Printf("Failed to fetch monitor rules: %v", err)
+		return domain.MonitorConfig{}, err
+	}
+	return config, nil
+}
+
+func (r *postgresRepository) CreateMonitorRule(ctx context.Context, rule domain.MonitorRule) (domain.MonitorRule, error) {
+	query := `
+        INSERT INTO monitor_rules (name, priority, container, labels, exclude, interval_seconds, probes, options, max_latency_ms)
+        VALUES (:name, :priority, :container, :labels, :exclude, :interval_seconds, :probes, :options, :max_latency_ms)
+        RETURNING ` + monitorRuleColumns
+	return r.changeMonitorRule(ctx, query, rule)
+}
+
+func (r *postgresRepository) UpdateMonitorRule(ctx context.Context, rule domain.MonitorRule) (domain.MonitorRule, error) {
+	query := `
+        UPDATE monitor_rules
+        SET name = :name, priority = :priority, container = :container, labels = :labels, exclude = :exclude,
+            interval_seconds = :interval_seconds, probes = :probes, options = :options,
+            max_latency_ms = :max_latency_ms, updated_at = NOW()
+        WHERE id = :id
+        RETURNING ` + monitorRuleColumns
+	return r.changeMonitorRule(ctx, query, rule)
+}
+
+func (r *postgresRepository) DeleteMonitorRule(ctx context.Context, id int) error {
+	_, err := r.changeMonitorRule(ctx, `DELETE FROM monitor_rules WHERE id = :id RETURNING `+monitorRuleColumns, domain.MonitorRule{ID: id})
+	return err
+}
+
+// changeMonitorRule выполняет изменение правила и увеличивает версию
+// конфигурации в одной транзакции.
+func (r *postgresRepository) changeMonitorRule(ctx context.Context, query string, rule domain.MonitorRule) (domain.MonitorRule, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.MonitorRule{}, err
+	}
+	defer tx.Rollback()
+
+	query, args, err := tx.BindNamed(query, rule)
+	if err != nil {
+		return domain.MonitorRule{}, err
+	}
+	var saved domain.MonitorRule
+	if err := tx.GetContext(ctx, &saved, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.MonitorRule{}, domain.ErrRuleNotFound
+		}
+		log.Printf("Failed to save monitor rule: %v", err)
+		return domain.MonitorRule{}, err
+	}
+	// Блокировка строки версии заодно упорядочивает одновременные изменения
+	query = `
+        INSERT INTO monitor_config (id, version) VALUES (TRUE, 1)
+        ON CONFLICT (id) DO UPDATE SET version = monitor_config.version + 1
+    `
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		log.Printf("Failed to bump monitor config version: %v", err)
+		return domain.MonitorRule{}, err
+	}
+	return saved, tx.Commit()
+}
+
+func (r *postgresRepository) SaveDiagnostics(ctx context.Context, diagnostics []domain.Diagnostic) error {
+	query := `
+        INSERT INTO diagnostics (container_id, identity, operation, target, network, status, error, details, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `
+	for _, d := range diagnostics {
+		var details interface{}
+		if len(d.Details) > 0 {
+			details = []byte(d.Details)
+		}
+		_, err := r.db.ExecContext(ctx, query, d.ContainerID, d.Identity, d.Operation, d.Target, d.Network,
+			d.Status, d.Error, details, d.Timestamp)
+		if err != nil {
+			log.Printf("Failed to save diagnostic: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetDiagnostics(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.Diagnostic, error) {
+	query := `
+        SELECT id, container_id, identity, operation, target, network, status, error, details, created_at
+        FROM diagnostics
+        WHERE container_id = $1 AND created_at >= $2
+        ORDER BY created_at DESC, id
+        LIMIT $3
+    `
+	var diagnostics []domain.Diagnostic
+	err := r.db.SelectContext(ctx, &diagnostics, query, containerID, since, limit)
+	if err != nil {
+		log.Printf("Failed to fetch diagnostics: %v", err)
+		return nil, err
+	}
+	return diagnostics, nil
+}
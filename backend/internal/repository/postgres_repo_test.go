@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// rowsDriver отдаёт на любой запрос одни и те же строки: так проверяется
+// сопоставление столбцов запроса с полями структур без настоящей базы.
+type rowsDriver struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d *rowsDriver) Open(string) (driver.Conn, error) { return &rowsConn{d}, nil }
+
+type rowsConn struct{ d *rowsDriver }
+
+func (c *rowsConn) Prepare(string) (driver.Stmt, error) { return &rowsStmt{c.d}, nil }
+func (c *rowsConn) Close() error                        { return nil }
+func (c *rowsConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type rowsStmt struct{ d *rowsDriver }
+
+func (s *rowsStmt) Close() error                               { return nil }
+func (s *rowsStmt) NumInput() int                              { return -1 }
+func (s *rowsStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (s *rowsStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fixedRows{columns: s.d.columns, rows: s.d.rows}, nil
+}
+
+type fixedRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fixedRows) Columns() []string { return r.columns }
+func (r *fixedRows) Close() error      { return nil }
+func (r *fixedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestGetPingHistoryScansReason(t *testing.T) {
+	now := time.Now()
+	sql.Register("ping_history_rows", &rowsDriver{
+		columns: []string{"id", "ip_address", "network", "family", "mode", "details", "last_ping", "ping_time", "status", "reason"},
+		rows: [][]driver.Value{
+			{int64(1), "172.18.0.3", "app", "ipv4", "external", nil, now, 0.001, false, "probe_impaired"},
+		},
+	})
+	db, err := sqlx.Open("ping_history_rows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	records, err := NewPostgresRepository(db).GetPingHistory(context.Background(), "abc", time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("GetPingHistory() error: %v", err)
+	}
+	if len(records) != 1 || records[0].Reason != "probe_impaired" || records[0].IPAddress != "172.18.0.3" {
+		t.Errorf("GetPingHistory() = %+v, want one record with reason probe_impaired", records)
+	}
+}
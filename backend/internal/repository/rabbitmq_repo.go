@@ -2,9 +2,13 @@ package repository
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"log"
+	"strconv"
+	"sync"
 	"time"
 
 	"backend/domain"
@@ -13,14 +17,48 @@ import (
 
 type RabbitMQRepository interface {
 	ConsumePingResults(ctx context.Context) (<-chan domain.PingResult, error)
+	ConsumeContainerStats(ctx context.Context) (<-chan domain.ContainerStats, error)
+	ConsumeContainerEvents(ctx context.Context) (<-chan domain.ContainerEvent, error)
+	ConsumeServiceHealth(ctx context.Context) (<-chan domain.ServiceHealth, error)
+	// PublishConfigChanged уведомляет пингеры о новой версии конфигурации мониторинга.
+	PublishConfigChanged(version int64) error
+	// Call отправляет запрос в очередь queue и ждёт ответа, пока не истечёт ctx.
+	Call(ctx context.Context, queue string, request interface{}, response interface{}) error
 	Close() error
 }
 
+// queues — очереди, из которых читает бэкенд.
+var queues = []string{"ping_results", "container_stats", "container_events", "service_health"}
+
+// configExchange — fanout-обменник уведомлений об изменении конфигурации;
+// каждый пингер привязывает к нему свою временную очередь.
+const configExchange = "monitor_config"
+
 type rabbitMQRepository struct {
 	conn *amqp.Connection
 	ch   *amqp.Channel
+	// publishMu сериализует публикации: канал общий с потребителями
+	publishMu sync.Mutex
+
+	// rpcCh — отдельный канал для запросов: ответы приходят через direct
+	// reply-to, который работает только на канале, где опубликован запрос
+	rpcCh   *amqp.Channel
+	rpcMu   sync.Mutex
+	pending map[string]chan []byte
 }
 
+// Очереди запросов к пингерам: внеочередная проверка и сетевая диагностика.
+const (
+	ProbeQueue      = "probe_requests"
+	DiagnosticQueue = "diagnostic_requests"
+)
+
+// replyQueue — псевдоочередь direct reply-to RabbitMQ.
+const replyQueue = "amq.rabbitmq.reply-to"
+
+// ErrCallTimeout возвращается, если ответ не пришёл до истечения контекста.
+var ErrCallTimeout = errors.New("rpc call timed out")
+
 func NewRabbitMQRepository(rabbitMQURL string) (RabbitMQRepository, error) {
 	var conn *amqp.Connection
 	var err error
@@ -44,11 +82,28 @@ func NewRabbitMQRepository(rabbitMQURL string) (RabbitMQRepository, error) {
 		return nil, err
 	}
 
-	_, err = ch.QueueDeclare(
-		"ping_results", // name
+	for _, name := range queues {
+		_, err = ch.QueueDeclare(
+			name,  // name
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		if err != nil {
+			ch.Close()
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	err = ch.ExchangeDeclare(
+		configExchange, // name
+		"fanout",       // kind
 		true,           // durable
-		false,          // delete when unused
-		false,          // exclusive
+		false,          // auto-deleted
+		false,          // internal
 		false,          // no-wait
 		nil,            // arguments
 	)
@@ -58,47 +113,205 @@ func NewRabbitMQRepository(rabbitMQURL string) (RabbitMQRepository, error) {
 		return nil, err
 	}
 
-	return &rabbitMQRepository{
-		conn: conn,
-		ch:   ch,
-	}, nil
+	repo := &rabbitMQRepository{
+		conn:    conn,
+		ch:      ch,
+		pending: make(map[string]chan []byte),
+	}
+	if err := repo.startReplies(); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// startReplies открывает канал запросов и раздаёт ответы ожидающим вызовам.
+func (r *rabbitMQRepository) startReplies() error {
+	ch, err := r.conn.Channel()
+	if err != nil {
+		return err
+	}
+	// Запросы не копятся, если пингеров нет: сообщения живут не дольше таймаута вызова
+	for _, name := range []string{ProbeQueue, DiagnosticQueue} {
+		_, err = ch.QueueDeclare(
+			name,  // name
+			false, // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		if err != nil {
+			ch.Close()
+			return err
+		}
+	}
+	replies, err := ch.Consume(
+		replyQueue, // queue
+		"",         // consumer
+		true,       // auto-ack
+		false,      // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
+	)
+	if err != nil {
+		ch.Close()
+		return err
+	}
+	r.rpcCh = ch
+
+	go func() {
+		for msg := range replies {
+			r.rpcMu.Lock()
+			waiter, ok := r.pending[msg.CorrelationId]
+			delete(r.pending, msg.CorrelationId)
+			r.rpcMu.Unlock()
+			if !ok {
+				log.Printf("Dropping late RPC reply %s", msg.CorrelationId)
+				continue
+			}
+			waiter <- msg.Body
+		}
+		log.Println("RabbitMQ reply channel closed")
+	}()
+	return nil
+}
+
+func (r *rabbitMQRepository) Call(ctx context.Context, queue string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	id, err := correlationID()
+	if err != nil {
+		return err
+	}
+
+	publishing := amqp.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: id,
+		ReplyTo:       replyQueue,
+		Body:          body,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		// Запрос, который никто не успел взять, брокер удалит сам
+		ttl := time.Until(deadline).Milliseconds()
+		if ttl <= 0 {
+			return ErrCallTimeout
+		}
+		publishing.Expiration = strconv.FormatInt(ttl, 10)
+	}
+
+	waiter := make(chan []byte, 1)
+	r.rpcMu.Lock()
+	r.pending[id] = waiter
+	err = r.rpcCh.Publish("", queue, false, false, publishing)
+	r.rpcMu.Unlock()
+	defer func() {
+		r.rpcMu.Lock()
+		delete(r.pending, id)
+		r.rpcMu.Unlock()
+	}()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ErrCallTimeout
+	case reply := <-waiter:
+		return json.Unmarshal(reply, response)
+	}
+}
+
+// correlationID возвращает случайный идентификатор запроса.
+func correlationID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
 
 func (r *rabbitMQRepository) ConsumePingResults(ctx context.Context) (<-chan domain.PingResult, error) {
-	msgs, err := r.ch.Consume(
-		"ping_results", // queue
-		"",             // consumer
-		false,          // auto-ack
-		false,          // exclusive
-		false,          // no-local
-		false,          // no-wait
-		nil,            // args
+	return consume[domain.PingResult](ctx, r.ch, "ping_results")
+}
+
+func (r *rabbitMQRepository) ConsumeContainerStats(ctx context.Context) (<-chan domain.ContainerStats, error) {
+	return consume[domain.ContainerStats](ctx, r.ch, "container_stats")
+}
+
+func (r *rabbitMQRepository) ConsumeContainerEvents(ctx context.Context) (<-chan domain.ContainerEvent, error) {
+	return consume[domain.ContainerEvent](ctx, r.ch, "container_events")
+}
+
+func (r *rabbitMQRepository) ConsumeServiceHealth(ctx context.Context) (<-chan domain.ServiceHealth, error) {
+	return consume[domain.ServiceHealth](ctx, r.ch, "service_health")
+}
+
+func (r *rabbitMQRepository) PublishConfigChanged(version int64) error {
+	body, err := json.Marshal(map[string]int64{"version": version})
+	if err != nil {
+		return err
+	}
+
+	r.publishMu.Lock()
+	defer r.publishMu.Unlock()
+	return r.ch.Publish(
+		configExchange, // exchange
+		"",             // routing key
+		false,          // mandatory
+		false,          // immediate
+		amqp.Publishing{
+			ContentType: "application/json",
+			Body:        body,
+		},
+	)
+}
+
+// consume читает JSON-сообщения из очереди и подтверждает их после передачи в канал.
+func consume[T any](ctx context.Context, ch *amqp.Channel, queue string) (<-chan T, error) {
+	msgs, err := ch.Consume(
+		queue, // queue
+		"",    // consumer
+		false, // auto-ack
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	results := make(chan domain.PingResult)
+	results := make(chan T)
 	go func() {
 		defer close(results)
 		for {
 			select {
 			case <-ctx.Done():
-				log.Println("Stopping RabbitMQ consumption due to context cancellation")
+				log.Printf("Stopping consumption of %s due to context cancellation", queue)
 				return
 			case msg, ok := <-msgs:
 				if !ok {
-					log.Println("RabbitMQ channel closed")
+					log.Printf("RabbitMQ channel for %s closed", queue)
 					return
 				}
-				var result domain.PingResult
+				var result T
 				err := json.Unmarshal(msg.Body, &result)
 				if err != nil {
-					log.Printf("Failed to unmarshal message: %v", err)
+					log.Printf("Failed to unmarshal message from %s: %v", queue, err)
+					msg.Ack(false)
 					continue
 				}
-				results <- result
-				msg.Ack(false)
+				select {
+				case results <- result:
+					msg.Ack(false)
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
@@ -107,6 +320,12 @@ func (r *rabbitMQRepository) ConsumePingResults(ctx context.Context) (<-chan dom
 }
 
 func (r *rabbitMQRepository) Close() error {
+	if r.rpcCh != nil {
+		if err := r.rpcCh.Close(); err != nil {
+			log.Printf("Failed to close RabbitMQ RPC channel: %v", err)
+			return err
+		}
+	}
 	if r.ch != nil {
 		if err := r.ch.Close(); err != nil {
 			log.Printf("Failed to close RabbitMQ channel: %v", err)
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log"
 	"sync"
+	"time"
 
 	"backend/domain"
 	"backend/internal/repository"
@@ -12,12 +14,15 @@ import (
 type BackendService struct {
 	rabbitRepo repository.RabbitMQRepository
 	dbRepo     repository.PostgresRepository
+	// grace — сколько цель может молчать, прежде чем считается пропавшей
+	grace time.Duration
 }
 
-func NewBackendService(rabbitRepo repository.RabbitMQRepository, dbRepo repository.PostgresRepository) *BackendService {
+func NewBackendService(rabbitRepo repository.RabbitMQRepository, dbRepo repository.PostgresRepository, grace time.Duration) *BackendService {
 	return &BackendService{
 		rabbitRepo: rabbitRepo,
 		dbRepo:     dbRepo,
+		grace:      grace,
 	}
 }
 
@@ -40,14 +45,409 @@ func (s *BackendService) StartConsuming(ctx context.Context, wg *sync.WaitGroup)
 				log.Println("Results channel closed")
 				return
 			}
-			result.Status = true // Assuming success if we received the result
+			if result.Container != nil && result.Container.ID != "" {
+				seenAt := result.Timestamp
+				if seenAt.IsZero() {
+					seenAt = time.Now()
+				}
+				if err := s.dbRepo.SaveContainerMeta(ctx, *result.Container, seenAt); err != nil {
+					log.Printf("Failed to save container metadata: %v", err)
+				}
+			}
+			// Результат без адреса несёт только состояние цели (остановлена или
+			// исключена из проверок), оно уже сохранено в инвентаре
+			if result.IP == "" {
+				continue
+			}
+			suppressImpaired(&result)
 			if err := s.dbRepo.SavePingResult(ctx, result); err != nil {
 				log.Printf("Failed to save ping result: %v", err)
 			}
+			if record, ok := addressRecord(result); ok {
+				if err := s.dbRepo.SaveAddress(ctx, record); err != nil {
+					log.Printf("Failed to save address history: %v", err)
+				}
+			}
+			if probes := probeResults(result); len(probes) > 0 {
+				if err := s.dbRepo.SaveProbeResults(ctx, probes); err != nil {
+					log.Printf("Failed to save probe results: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// StartConsumingStats сохраняет выборки потребления ресурсов от пингера.
+func (s *BackendService) StartConsumingStats(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	statsChan, err := s.rabbitRepo.ConsumeContainerStats(ctx)
+	if err != nil {
+		log.Printf("Failed to start consuming container stats: %v", err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping stats consumption...")
+			return
+		case stats, ok := <-statsChan:
+			if !ok {
+				log.Println("Stats channel closed")
+				return
+			}
+			if err := s.dbRepo.SaveContainerStats(ctx, stats); err != nil {
+				log.Printf("Failed to save container stats: %v", err)
+			}
+		}
+	}
+}
+
+// StartConsumingEvents сохраняет события жизненного цикла контейнеров.
+func (s *BackendService) StartConsumingEvents(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	eventsChan, err := s.rabbitRepo.ConsumeContainerEvents(ctx)
+	if err != nil {
+		log.Printf("Failed to start consuming container events: %v", err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping event consumption...")
+			return
+		case event, ok := <-eventsChan:
+			if !ok {
+				log.Println("Events channel closed")
+				return
+			}
+			if event.ContainerID == "" {
+				continue
+			}
+			if err := s.dbRepo.SaveContainerEvent(ctx, event); err != nil {
+				log.Printf("Failed to save container event: %v", err)
+			}
+		}
+	}
+}
+
+// StartConsumingServiceHealth сохраняет сводки доступности сервисов Swarm.
+func (s *BackendService) StartConsumingServiceHealth(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	healthChan, err := s.rabbitRepo.ConsumeServiceHealth(ctx)
+	if err != nil {
+		log.Printf("Failed to start consuming service health: %v", err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping service health consumption...")
+			return
+		case health, ok := <-healthChan:
+			if !ok {
+				log.Println("Service health channel closed")
+				return
+			}
+			if err := s.dbRepo.SaveServiceHealth(ctx, health); err != nil {
+				log.Printf("Failed to save service health: %v", err)
+			}
+		}
+	}
+}
+
+// suppressImpaired помечает сбой, случившийся при неисправной сети самого
+// пингера: такой результат не переводит контейнер в недоступные.
+func suppressImpaired(result *domain.PingResult) {
+	if result.Status || result.Reason != "" || result.EnvironmentHealthy == nil || *result.EnvironmentHealthy {
+		return
+	}
+	result.Reason = domain.ReasonProbeImpaired
+}
+
+// addressRecord возвращает адрес логического контейнера из результата внешней проверки.
+func addressRecord(result domain.PingResult) (domain.AddressRecord, bool) {
+	if result.IP == "" || result.Container == nil || result.Container.Identity == "" {
+		return domain.AddressRecord{}, false
+	}
+	if result.Mode != "" && result.Mode != "external" {
+		return domain.AddressRecord{}, false
+	}
+	seenAt := result.Timestamp
+	if seenAt.IsZero() {
+		seenAt = time.Now()
+	}
+	return domain.AddressRecord{
+		Identity:    result.Container.Identity,
+		ContainerID: result.ContainerID,
+		Network:     result.Network,
+		Family:      result.Family,
+		IPAddress:   result.IP,
+		LastSeen:    seenAt,
+	}, true
+}
+
+// probeResults дополняет результаты проверок из сообщения пингера данными для хранения.
+func probeResults(result domain.PingResult) []domain.ProbeResult {
+	probes := make([]domain.ProbeResult, 0, len(result.Probes))
+	for _, probe := range result.Probes {
+		probe.ContainerID = result.ContainerID
+		probe.CheckedAt = result.Timestamp
+		probes = append(probes, probe)
+	}
+	return probes
+}
+
+// StartStaleWatcher периодически помечает исчезнувшие и остановленные контейнеры как недоступные.
+func (s *BackendService) StartStaleWatcher(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(s.grace / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping stale container watcher...")
+			return
+		case <-ticker.C:
+			marked, err := s.dbRepo.MarkStaleContainers(ctx, s.grace)
+			if err != nil {
+				log.Printf("Failed to mark stale containers: %v", err)
+				continue
+			}
+			if marked > 0 {
+				log.Printf("Marked %d container(s) as down", marked)
+			}
 		}
 	}
 }
 
 func (s *BackendService) GetAllContainers(ctx context.Context) ([]domain.Container, error) {
-	return s.dbRepo.GetAllContainers(ctx)
+	containers, err := s.dbRepo.GetAllContainers(ctx, s.grace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range containers {
+		containers[i].ComputeCondition()
+	}
+	return containers, nil
+}
+
+func (s *BackendService) GetInventory(ctx context.Context) ([]domain.ContainerMeta, error) {
+	return s.dbRepo.GetInventory(ctx)
+}
+
+func (s *BackendService) GetProbeResults(ctx context.Context, containerID string, limit int) ([]domain.ProbeResult, error) {
+	return s.dbRepo.GetProbeResults(ctx, containerID, limit)
+}
+
+func (s *BackendService) GetPingHistory(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.PingRecord, error) {
+	return s.dbRepo.GetPingHistory(ctx, containerID, since, limit)
+}
+
+func (s *BackendService) GetContainerStats(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.ContainerStats, error) {
+	return s.dbRepo.GetContainerStats(ctx, containerID, since, limit)
+}
+
+func (s *BackendService) GetContainerEvents(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.ContainerEvent, error) {
+	return s.dbRepo.GetContainerEvents(ctx, containerID, since, limit)
+}
+
+func (s *BackendService) GetTimeline(ctx context.Context, since time.Time, limit int) ([]domain.TimelineEntry, error) {
+	return s.dbRepo.GetTimeline(ctx, since, limit)
+}
+
+func (s *BackendService) GetAddressHistory(ctx context.Context, containerID string, limit int) ([]domain.AddressRecord, error) {
+	return s.dbRepo.GetAddressHistory(ctx, containerID, limit)
+}
+
+func (s *BackendService) GetHosts(ctx context.Context) ([]domain.HostSummary, error) {
+	return s.dbRepo.GetHosts(ctx)
+}
+
+func (s *BackendService) GetServices(ctx context.Context) ([]domain.ServiceHealth, error) {
+	return s.dbRepo.GetServices(ctx)
+}
+
+func (s *BackendService) GetServiceHealth(ctx context.Context, serviceID string, since time.Time, limit int) ([]domain.ServiceHealth, error) {
+	return s.dbRepo.GetServiceHealth(ctx, serviceID, since, limit)
+}
+
+func (s *BackendService) GetMonitorRules(ctx context.Context) ([]domain.MonitorRule, error) {
+	return s.dbRepo.GetMonitorRules(ctx)
+}
+
+// GetMonitorConfig отдаёт конфигурацию для пингеров. Интервалы правил,
+// сохранённых при другом CONTAINER_GRACE_PERIOD, урезаются до допустимых.
+func (s *BackendService) GetMonitorConfig(ctx context.Context) (domain.MonitorConfig, error) {
+	config, err := s.dbRepo.GetMonitorConfig(ctx)
+	if err != nil {
+		return domain.MonitorConfig{}, err
+	}
+	limit := s.maxInterval()
+	for i := range config.Rules {
+		if config.Rules[i].Interval > limit {
+			config.Rules[i].Interval = limit
+		}
+	}
+	return config, nil
+}
+
+// maxInterval возвращает наибольший интервал правила в секундах: половина
+// grace, чтобы одна пропущенная проверка не делала цель пропавшей.
+func (s *BackendService) maxInterval() int {
+	return max(int(s.grace/2/time.Second), 1)
+}
+
+func (s *BackendService) validateRule(rule domain.MonitorRule) error {
+	if rule.Interval > s.maxInterval() {
+		return domain.ErrRuleInterval
+	}
+	return nil
+}
+
+func (s *BackendService) CreateMonitorRule(ctx context.Context, rule domain.MonitorRule) (domain.MonitorRule, error) {
+	if err := s.validateRule(rule); err != nil {
+		return domain.MonitorRule{}, err
+	}
+	saved, err := s.dbRepo.CreateMonitorRule(ctx, rule)
+	if err != nil {
+		return domain.MonitorRule{}, err
+	}
+	s.notifyConfigChanged(ctx)
+	return saved, nil
+}
+
+func (s *BackendService) UpdateMonitorRule(ctx context.Context, rule domain.MonitorRule) (domain.MonitorRule, error) {
+	if err := s.validateRule(rule); err != nil {
+		return domain.MonitorRule{}, err
+	}
+	saved, err := s.dbRepo.UpdateMonitorRule(ctx, rule)
+	if err != nil {
+		return domain.MonitorRule{}, err
+	}
+	s.notifyConfigChanged(ctx)
+	return saved, nil
+}
+
+func (s *BackendService) DeleteMonitorRule(ctx context.Context, id int) error {
+	if err := s.dbRepo.DeleteMonitorRule(ctx, id); err != nil {
+		return err
+	}
+	s.notifyConfigChanged(ctx)
+	return nil
+}
+
+// notifyConfigChanged сообщает пингерам о новой конфигурации. Ошибка не
+// критична: пингеры периодически перечитывают конфигурацию сами.
+func (s *BackendService) notifyConfigChanged(ctx context.Context) {
+	config, err := s.dbRepo.GetMonitorConfig(ctx)
+	if err != nil {
+		log.Printf("Failed to read monitor config version: %v", err)
+		return
+	}
+	if err := s.rabbitRepo.PublishConfigChanged(config.Version); err != nil {
+		log.Printf("Failed to notify pingers about config version %d: %v", config.Version, err)
+	}
+}
+
+var (
+	// ErrContainerNotFound — ни один пингер не знает запрошенный контейнер.
+	ErrContainerNotFound = errors.New("container not found")
+	// ErrProbeTimeout — пингер не ответил на запрос проверки вовремя.
+	ErrProbeTimeout = errors.New("probe request timed out")
+)
+
+// monitored проверяет, что контейнер есть в инвентаре и не пропал. Пингер,
+// который не знает контейнер, возвращает запрос в очередь другим, поэтому
+// запрос о неизвестном контейнере иначе ждал бы до таймаута.
+func (s *BackendService) monitored(ctx context.Context, containerID string) error {
+	meta, err := s.dbRepo.GetContainerMeta(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	if meta == nil || meta.State == domain.StateVanished {
+		return ErrContainerNotFound
+	}
+	return nil
+}
+
+// ProbeNow просит пингер проверить контейнер вне расписания и ждёт результат
+// не дольше timeout. Результаты также приходят в ping_results и сохраняются как обычно.
+func (s *BackendService) ProbeNow(ctx context.Context, containerID string, timeout time.Duration) ([]domain.PingResult, error) {
+	if err := s.monitored(ctx, containerID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var response domain.ProbeResponse
+	err := s.rabbitRepo.Call(ctx, repository.ProbeQueue, domain.ProbeRequest{ContainerID: containerID}, &response)
+	if errors.Is(err, repository.ErrCallTimeout) {
+		return nil, ErrProbeTimeout
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch response.Error {
+	case "":
+		return response.Results, nil
+	case domain.ProbeErrorNotFound:
+		return nil, ErrContainerNotFound
+	default:
+		return nil, errors.New(response.Error)
+	}
+}
+
+// RunDiagnostics просит пингер выполнить сетевую диагностику контейнера и
+// сохраняет результаты. Инцидентов в системе пока нет, поэтому результаты
+// привязываются к контейнеру.
+func (s *BackendService) RunDiagnostics(ctx context.Context, containerID string, operations []string, timeout time.Duration) ([]domain.Diagnostic, error) {
+	if err := s.monitored(ctx, containerID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var response domain.DiagnosticResponse
+	request := domain.DiagnosticRequest{ContainerID: containerID, Operations: operations}
+	err := s.rabbitRepo.Call(ctx, repository.DiagnosticQueue, request, &response)
+	if errors.Is(err, repository.ErrCallTimeout) {
+		return nil, ErrProbeTimeout
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch response.Error {
+	case "":
+	case domain.ProbeErrorNotFound:
+		return nil, ErrContainerNotFound
+	default:
+		return nil, errors.New(response.Error)
+	}
+
+	diagnostics := make([]domain.Diagnostic, 0, len(response.Results))
+	for _, d := range response.Results {
+		d.ContainerID = containerID
+		d.Identity = response.Identity
+		if d.Timestamp.IsZero() {
+			d.Timestamp = time.Now()
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	// Сохраняем уже после ответа: контекст вызова мог истечь на пределе
+	if err := s.dbRepo.SaveDiagnostics(context.WithoutCancel(ctx), diagnostics); err != nil {
+		return nil, err
+	}
+	return diagnostics, nil
+}
+
+func (s *BackendService) GetDiagnostics(ctx context.Context, containerID string, since time.Time, limit int) ([]domain.Diagnostic, error) {
+	return s.dbRepo.GetDiagnostics(ctx, containerID, since, limit)
 }
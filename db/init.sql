@@ -1,10 +1,187 @@
+-- Схема идемпотентна: столбцы, появившиеся после создания таблицы, добавляются
+-- через ADD COLUMN IF NOT EXISTS до индексов по ним. Docker применяет файл только
+-- к пустой базе; существующую обновляют вручную:
+--   docker compose exec db psql -U admin -d monitoring -f /docker-entrypoint-initdb.d/init.sql
+
 CREATE TABLE IF NOT EXISTS containers (
     id SERIAL PRIMARY KEY,
+    container_id VARCHAR(64) NOT NULL DEFAULT '',
+    identity VARCHAR(512) NOT NULL DEFAULT '',
+    host VARCHAR(255) NOT NULL DEFAULT '',
     ip_address VARCHAR(255) NOT NULL,
+    network VARCHAR(255) NOT NULL DEFAULT '',
+    family VARCHAR(8) NOT NULL DEFAULT 'ipv4',
+    mode VARCHAR(16) NOT NULL DEFAULT 'external',
+    details JSONB,
     last_ping TIMESTAMP NOT NULL DEFAULT NOW(),
     ping_time FLOAT not null,
-    status BOOLEAN NOT NULL
+    status BOOLEAN NOT NULL,
+    reason VARCHAR(32) NOT NULL DEFAULT ''
+);
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS container_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS identity VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS host VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS network VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS family VARCHAR(8) NOT NULL DEFAULT 'ipv4';
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS mode VARCHAR(16) NOT NULL DEFAULT 'external';
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS details JSONB;
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS reason VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS containers_history_idx ON containers (container_id, last_ping DESC);
+
+CREATE TABLE IF NOT EXISTS container_inventory (
+    container_id VARCHAR(64) PRIMARY KEY,
+    identity VARCHAR(512) NOT NULL DEFAULT '',
+    source VARCHAR(32) NOT NULL DEFAULT 'docker',
+    host VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    image VARCHAR(512) NOT NULL DEFAULT '',
+    image_digest VARCHAR(512) NOT NULL DEFAULT '',
+    compose_project VARCHAR(255) NOT NULL DEFAULT '',
+    compose_service VARCHAR(255) NOT NULL DEFAULT '',
+    labels JSONB NOT NULL DEFAULT '{}',
+    state VARCHAR(32) NOT NULL DEFAULT '',
+    exit_code INTEGER NOT NULL DEFAULT 0,
+    restart_count INTEGER NOT NULL DEFAULT 0,
+    oom_killed BOOLEAN NOT NULL DEFAULT FALSE,
+    health_status VARCHAR(16) NOT NULL DEFAULT '',
+    health_failing_streak INTEGER NOT NULL DEFAULT 0,
+    health_output TEXT NOT NULL DEFAULT '',
+    down BOOLEAN NOT NULL DEFAULT FALSE,
+    last_seen TIMESTAMP NOT NULL DEFAULT NOW(),
+    state_changed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS identity VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS source VARCHAR(32) NOT NULL DEFAULT 'docker';
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS host VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS exit_code INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS restart_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS oom_killed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS health_status VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS health_failing_streak INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS health_output TEXT NOT NULL DEFAULT '';
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS down BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS last_seen TIMESTAMP NOT NULL DEFAULT NOW();
+ALTER TABLE container_inventory ADD COLUMN IF NOT EXISTS state_changed_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS container_inventory_identity_idx ON container_inventory (identity);
+
+CREATE TABLE IF NOT EXISTS address_history (
+    id SERIAL PRIMARY KEY,
+    identity VARCHAR(512) NOT NULL,
+    container_id VARCHAR(64) NOT NULL,
+    network VARCHAR(255) NOT NULL DEFAULT '',
+    family VARCHAR(8) NOT NULL DEFAULT 'ipv4',
+    ip_address VARCHAR(255) NOT NULL,
+    first_seen TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS address_history_identity_idx ON address_history (identity, network, family, last_seen DESC);
+
+CREATE TABLE IF NOT EXISTS probe_results (
+    id SERIAL PRIMARY KEY,
+    container_id VARCHAR(64) NOT NULL,
+    probe_type VARCHAR(32) NOT NULL,
+    target VARCHAR(512) NOT NULL DEFAULT '',
+    status BOOLEAN NOT NULL,
+    latency FLOAT NOT NULL DEFAULT 0,
+    failure VARCHAR(32) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    details JSONB,
+    checked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS probe_results_container_idx ON probe_results (container_id, checked_at DESC);
+
+CREATE TABLE IF NOT EXISTS container_stats (
+    id SERIAL PRIMARY KEY,
+    container_id VARCHAR(64) NOT NULL,
+    host VARCHAR(255) NOT NULL DEFAULT '',
+    sampled_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    cpu_percent FLOAT NOT NULL DEFAULT 0,
+    online_cpus INTEGER NOT NULL DEFAULT 0,
+    memory_usage BIGINT NOT NULL DEFAULT 0,
+    memory_limit BIGINT NOT NULL DEFAULT 0,
+    memory_percent FLOAT NOT NULL DEFAULT 0,
+    network_rx BIGINT NOT NULL DEFAULT 0,
+    network_tx BIGINT NOT NULL DEFAULT 0,
+    block_read BIGINT NOT NULL DEFAULT 0,
+    block_write BIGINT NOT NULL DEFAULT 0,
+    pids BIGINT NOT NULL DEFAULT 0
+);
+ALTER TABLE container_stats ADD COLUMN IF NOT EXISTS host VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS container_stats_container_idx ON container_stats (container_id, sampled_at DESC);
+
+CREATE TABLE IF NOT EXISTS container_events (
+    id SERIAL PRIMARY KEY,
+    container_id VARCHAR(64) NOT NULL,
+    host VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(32) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    image VARCHAR(512) NOT NULL DEFAULT '',
+    network VARCHAR(255) NOT NULL DEFAULT '',
+    exit_code INTEGER,
+    signal VARCHAR(16) NOT NULL DEFAULT '',
+    health_status VARCHAR(16) NOT NULL DEFAULT '',
+    event_time TIMESTAMP NOT NULL DEFAULT NOW()
+);
+ALTER TABLE container_events ADD COLUMN IF NOT EXISTS host VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS container_events_container_idx ON container_events (container_id, event_time DESC);
+CREATE INDEX IF NOT EXISTS container_events_time_idx ON container_events (event_time DESC);
+
+CREATE TABLE IF NOT EXISTS service_health (
+    id SERIAL PRIMARY KEY,
+    service_id VARCHAR(64) NOT NULL,
+    service_name VARCHAR(255) NOT NULL DEFAULT '',
+    desired INTEGER NOT NULL DEFAULT 0,
+    running INTEGER NOT NULL DEFAULT 0,
+    reachable INTEGER NOT NULL DEFAULT 0,
+    status BOOLEAN NOT NULL,
+    checked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS service_health_service_idx ON service_health (service_id, checked_at DESC);
+
+CREATE TABLE IF NOT EXISTS diagnostics (
+    id SERIAL PRIMARY KEY,
+    container_id VARCHAR(64) NOT NULL,
+    identity VARCHAR(512) NOT NULL DEFAULT '',
+    operation VARCHAR(16) NOT NULL,
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    network VARCHAR(255) NOT NULL DEFAULT '',
+    status BOOLEAN NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    details JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS diagnostics_container_idx ON diagnostics (container_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS monitor_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    container VARCHAR(255) NOT NULL DEFAULT '',
+    labels JSONB NOT NULL DEFAULT '{}',
+    exclude BOOLEAN NOT NULL DEFAULT FALSE,
+    interval_seconds INTEGER NOT NULL DEFAULT 0,
+    probes JSONB NOT NULL DEFAULT '[]',
+    options JSONB NOT NULL DEFAULT '{}',
+    max_latency_ms INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Версия конфигурации мониторинга, увеличивается при каждом изменении правил
+-- в той же транзакции. Строка всегда одна
+CREATE TABLE IF NOT EXISTS monitor_config (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version BIGINT NOT NULL DEFAULT 0
 );
+INSERT INTO monitor_config (id, version) VALUES (TRUE, 0) ON CONFLICT (id) DO NOTHING;
 
 CREATE TABLE IF NOT EXISTS account (
     id serial primary key,
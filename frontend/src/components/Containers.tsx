@@ -12,6 +12,22 @@ import {
     Paper,
     Alert,
 } from '@mui/material';
+import type { ContainerCondition } from '../types';
+
+const conditionLabels: Record<ContainerCondition, string> = {
+    up: 'Up',
+    unreachable: 'Unreachable',
+    no_shared_network: 'No shared network',
+    slow: 'Slow',
+    probe_impaired: 'Not checked (pinger impaired)',
+    unhealthy: 'Unhealthy',
+    down: 'Down',
+};
+
+const conditionLabel = (container: any): string => {
+    const condition: string = container.condition || (container.status ? 'up' : 'unreachable');
+    return conditionLabels[condition as ContainerCondition] || condition;
+};
 
 const Containers: React.FC = () => {
     const [containers, setContainers] = useState<any[]>([]);
@@ -49,18 +65,28 @@ const Containers: React.FC = () => {
                 <Table>
                     <TableHead>
                         <TableRow>
+                            <TableCell>Name</TableCell>
+                            <TableCell>Image</TableCell>
                             <TableCell>IP Address</TableCell>
+                            <TableCell>Network</TableCell>
+                            <TableCell>Mode</TableCell>
                             <TableCell>Last Ping</TableCell>
                             <TableCell>Status</TableCell>
+                            <TableCell>Health</TableCell>
                             <TableCell>Ping Time</TableCell>
                         </TableRow>
                     </TableHead>
                     <TableBody>
                         {containers.map((container) => (
                             <TableRow key={container.id}>
+                                <TableCell>{container.name || container.ip_address}</TableCell>
+                                <TableCell>{container.image || 'N/A'}</TableCell>
                                 <TableCell>{container.ip_address}</TableCell>
+                                <TableCell>{container.network || 'N/A'}</TableCell>
+                                <TableCell>{container.mode === 'netns' ? 'In-container' : 'External'}</TableCell>
                                 <TableCell>{container.last_ping}</TableCell>
-                                <TableCell>{container.status ? 'Online' : 'Offline'}</TableCell>
+                                <TableCell>{conditionLabel(container)}</TableCell>
+                                <TableCell>{container.health_status || 'N/A'}</TableCell>
                                 <TableCell>{container.ping_time || 'N/A'}</TableCell>
                             </TableRow>
                         ))}
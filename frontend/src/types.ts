@@ -1,6 +1,19 @@
+export type ContainerCondition = 'up' | 'unreachable' | 'no_shared_network' | 'slow' | 'probe_impaired' | 'unhealthy' | 'down';
+
 export interface Container {
     id: number;
+    container_id: string;
+    name: string;
+    image: string;
+    compose_service: string;
+    state: string;
+    down: boolean;
+    health_status: string;
+    condition: ContainerCondition;
     ip_address: string;
+    network: string;
+    family: string;
+    mode: string;
     last_ping: string;
     status: boolean;
     ping_time: string;
@@ -3,14 +3,20 @@ package main
 import (
 	"context"
 	"log"
+	"net"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"pinger/internal/delivery"
 	"pinger/internal/repository"
+	"pinger/pinger"
+	"pinger/probe"
 )
 
 func main() {
@@ -22,29 +28,135 @@ func main() {
 	if err != nil {
 		log.Fatal("PING_TIME environment variable is not set")
 	}
-	interval := time.Duration(seconds) * time.Second
+	cfg := pinger.Config{
+		Interval:       time.Duration(seconds) * time.Second,
+		Workers:        int(envInt64("PING_WORKERS", 10)),
+		ProbeTimeout:   time.Duration(envInt64("PING_TIMEOUT_MS", 1000)) * time.Millisecond,
+		Jitter:         envFloat("PING_JITTER", 0.5),
+		ResyncInterval: time.Duration(envInt64("RESYNC_INTERVAL", 300)) * time.Second,
+		Networks:       envList("PROBE_NETWORKS"),
+		IPv6:           os.Getenv("PROBE_IPV6") != "false",
+		Netns:          os.Getenv("NETNS_ENABLED") == "true",
+		StatsInterval:  time.Duration(envInt64("STATS_INTERVAL", 30)) * time.Second,
+		SelfID:         os.Getenv("SELF_CONTAINER_ID"),
+		AutoJoin:       os.Getenv("AUTO_JOIN_NETWORKS") == "true",
+		// Опорные цели отличают сбой сети пингера от сбоя контейнеров
+		ReferenceGateway: os.Getenv("REFERENCE_GATEWAY") != "false",
+		ReferenceBroker:  brokerAddress(rabbitMQURL),
+		Canary:           os.Getenv("CANARY_TARGET"),
+	}
+
+	// Учётные данные для протокольных проверок хранилищ
+	var secrets map[string]probe.Credentials
+	if path := os.Getenv("SECRETS_FILE"); path != "" {
+		secrets, err = probe.LoadSecrets(path)
+		if err != nil {
+			log.Fatalf("Failed to load secrets: %v", err)
+		}
+	}
+
+	// Регистрация проверок; PROBES ограничивает набор встроенных
+	registry := probe.NewRegistry()
+	enabled := envList("PROBES")
+	defaults := probe.Defaults{
+		Timeout:    cfg.ProbeTimeout,
+		Secrets:    secrets,
+		ScriptsDir: os.Getenv("SCRIPTS_DIR"),
+		ScriptLimits: probe.ScriptLimits{
+			Timeout:   time.Duration(envInt64("SCRIPT_TIMEOUT_MS", 10000)) * time.Millisecond,
+			MaxSteps:  uint64(envInt64("SCRIPT_MAX_STEPS", 1_000_000)),
+			MaxCalls:  int(envInt64("SCRIPT_MAX_CALLS", 20)),
+			MaxMemory: uint64(envInt64("SCRIPT_MAX_MEMORY", 64<<20)),
+		},
+	}
+	for _, p := range probe.Builtin(defaults) {
+		if len(enabled) > 0 && !slices.Contains(enabled, p.Name()) {
+			continue
+		}
+		if err := registry.Register(p); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	outboxDir := os.Getenv("OUTBOX_DIR")
+	if outboxDir == "" {
+		outboxDir = "/var/lib/pinger/outbox"
+	}
+	segmentBytes := envInt64("OUTBOX_SEGMENT_BYTES", 1<<20)
+	maxBytes := envInt64("OUTBOX_MAX_BYTES", 64<<20)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Инициализация репозиториев
-	dockerRepo, err := repository.NewDockerRepository()
+	endpoints, err := repository.ParseDockerEndpoints(os.Getenv("DOCKER_HOSTS"), os.Getenv("DOCKER_HOST_NAME"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	rabbitRepo, err := repository.NewRabbitMQRepository(rabbitMQURL)
+
+	// DISCOVERY_MODE: containers — контейнеры каждого демона, swarm — задачи
+	// сервисов Swarm через менеджер, both — оба способа сразу
+	mode := os.Getenv("DISCOVERY_MODE")
+	if mode == "" {
+		mode = "containers"
+	}
+	if mode != "containers" && mode != "swarm" && mode != "both" {
+		log.Fatalf("Unknown DISCOVERY_MODE %q", mode)
+	}
+
+	var dockerRepos []repository.DockerRepository
+	if mode != "swarm" {
+		for _, endpoint := range endpoints {
+			dockerRepo, err := repository.NewDockerRepository(endpoint)
+			if err != nil {
+				log.Fatalf("Failed to initialize Docker client for host %s: %v", endpoint.Name, err)
+			}
+			dockerRepos = append(dockerRepos, dockerRepo)
+		}
+	}
+	// Outbox открывается первым: пока брокер недоступен, результаты копятся в нём
+	outboxRepo, err := repository.NewOutboxRepository(outboxDir, segmentBytes, maxBytes)
 	if err != nil {
-		log.Fatalf("Failed to initialize RabbitMQ repository: %v", err)
+		log.Fatalf("Failed to initialize outbox: %v", err)
 	}
+
+	rabbitRepo := repository.NewRabbitMQRepository(rabbitMQURL)
 	defer func() {
 		if err := rabbitRepo.Close(); err != nil {
 			log.Printf("Failed to close RabbitMQ connection: %v", err)
 		}
 	}()
 
+	// Конфигурация мониторинга от бэкенда; без BACKEND_URL действуют только метки и переменные окружения
+	var configRepo repository.ConfigRepository
+	if backendURL := os.Getenv("BACKEND_URL"); backendURL != "" {
+		cachePath := os.Getenv("CONFIG_CACHE_FILE")
+		if cachePath == "" {
+			cachePath = "/var/lib/pinger/config/monitor.json"
+		}
+		poll := time.Duration(envInt64("CONFIG_POLL_INTERVAL", 300)) * time.Second
+		configRepo = repository.NewConfigRepository(backendURL, os.Getenv("PINGER_TOKEN"), cachePath, rabbitMQURL, poll)
+	}
+
+	// Дополнительные источники целей
+	var sources []repository.TargetRepository
+	if mode != "containers" {
+		// Задачи Swarm берутся у первого демона из DOCKER_HOSTS, он должен быть менеджером
+		poll := time.Duration(envInt64("SWARM_POLL_INTERVAL", 30)) * time.Second
+		swarmRepo, err := repository.NewSwarmRepository(endpoints[0], poll)
+		if err != nil {
+			log.Fatalf("Failed to initialize Swarm discovery: %v", err)
+		}
+		sources = append(sources, swarmRepo)
+	}
+	if path := os.Getenv("TARGETS_FILE"); path != "" {
+		poll := time.Duration(envInt64("TARGETS_POLL_INTERVAL", 10)) * time.Second
+		sources = append(sources, repository.NewFileTargetRepository(path, poll))
+	}
+
 	// Запуск CLI
 	go func() {
-		delivery.RunCLI(ctx, dockerRepo, rabbitRepo, interval)
+		delivery.RunCLI(ctx, dockerRepos, rabbitRepo, outboxRepo, configRepo, cfg, registry, sources...)
 	}()
 
 	// Обработка сигналов завершения
@@ -59,3 +171,53 @@ func main() {
 	time.Sleep(2 * time.Second)
 	log.Println("Shutdown complete.")
 }
+
+func envInt64(name string, def int64) int64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Fatalf("%s must be an integer: %v", name, err)
+	}
+	return n
+}
+
+func envFloat(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("%s must be a number: %v", name, err)
+	}
+	return f
+}
+
+// brokerAddress возвращает host:port брокера из URL AMQP.
+func brokerAddress(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "5672"
+		if u.Scheme == "amqps" {
+			port = "5671"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func envList(name string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
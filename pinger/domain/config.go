@@ -0,0 +1,64 @@
+package domain
+
+import "path"
+
+// MonitorConfig is the monitoring configuration owned by the backend.
+// Version grows with every change of the rules.
+type MonitorConfig struct {
+	Version int64         `json:"version"`
+	Rules   []MonitorRule `json:"rules"`
+}
+
+// MonitorRule adjusts monitoring of the targets it matches. Zero Interval
+// and MaxLatencyMs keep the pinger defaults; Probes replaces the probe
+// selection and Options overrides probe labels such as monitor.http.path.
+type MonitorRule struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	// Container matches the target name, identity or ID and may be a path.Match pattern.
+	Container string `json:"container"`
+	// Labels must all be present on the target; an empty value matches any value.
+	Labels       map[string]string `json:"labels"`
+	Exclude      bool              `json:"exclude"`
+	Interval     int               `json:"interval_seconds"`
+	Probes       []string          `json:"probes"`
+	Options      map[string]string `json:"options"`
+	MaxLatencyMs int               `json:"max_latency_ms"`
+}
+
+// Match reports whether the rule selects the target described by meta.
+func (r MonitorRule) Match(meta ContainerMeta) bool {
+	if r.Container != "" && !matchAny(r.Container, meta.Name, meta.Identity, meta.ID) {
+		return false
+	}
+	for key, value := range r.Labels {
+		actual, ok := meta.Labels[key]
+		if !ok || (value != "" && value != actual) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(pattern string, values ...string) bool {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule returns the first rule matching meta. Rules are expected in priority order.
+func (c MonitorConfig) Rule(meta ContainerMeta) (MonitorRule, bool) {
+	for _, rule := range c.Rules {
+		if rule.Match(meta) {
+			return rule, true
+		}
+	}
+	return MonitorRule{}, false
+}
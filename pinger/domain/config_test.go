@@ -0,0 +1,71 @@
+package domain
+
+import "testing"
+
+func TestMonitorRuleMatch(t *testing.T) {
+	meta := ContainerMeta{
+		ID:       "3f2a9c1b7d4e",
+		Identity: "shop/web",
+		Name:     "shop-web-1",
+		Labels: map[string]string{
+			"tier":           "frontend",
+			"monitor.canary": "",
+		},
+	}
+
+	tests := []struct {
+		name string
+		rule MonitorRule
+		want bool
+	}{
+		{"empty rule matches everything", MonitorRule{}, true},
+		{"exact name", MonitorRule{Container: "shop-web-1"}, true},
+		{"name pattern", MonitorRule{Container: "shop-*"}, true},
+		{"identity pattern", MonitorRule{Container: "shop/*"}, true},
+		{"container id", MonitorRule{Container: "3f2a9c1b7d4e"}, true},
+		{"other container", MonitorRule{Container: "db-*"}, false},
+		{"invalid pattern", MonitorRule{Container: "shop-["}, false},
+		{"label value", MonitorRule{Labels: map[string]string{"tier": "frontend"}}, true},
+		{"label presence", MonitorRule{Labels: map[string]string{"tier": ""}}, true},
+		{"present label with empty value", MonitorRule{Labels: map[string]string{"monitor.canary": ""}}, true},
+		{"label value mismatch", MonitorRule{Labels: map[string]string{"tier": "backend"}}, false},
+		{"missing label", MonitorRule{Labels: map[string]string{"team": ""}}, false},
+		{
+			"name and labels",
+			MonitorRule{Container: "shop-*", Labels: map[string]string{"tier": "frontend"}},
+			true,
+		},
+		{
+			"name matches but labels do not",
+			MonitorRule{Container: "shop-*", Labels: map[string]string{"tier": "backend"}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Match(meta); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorConfigRule(t *testing.T) {
+	config := MonitorConfig{Rules: []MonitorRule{
+		{ID: 1, Container: "shop-*", Exclude: true},
+		{ID: 2},
+	}}
+
+	rule, ok := config.Rule(ContainerMeta{Name: "shop-web-1"})
+	if !ok || rule.ID != 1 {
+		t.Errorf("Rule(shop-web-1) = %d, %v, want rule 1", rule.ID, ok)
+	}
+	rule, ok = config.Rule(ContainerMeta{Name: "db-1"})
+	if !ok || rule.ID != 2 {
+		t.Errorf("Rule(db-1) = %d, %v, want rule 2", rule.ID, ok)
+	}
+	if _, ok := (MonitorConfig{}).Rule(ContainerMeta{Name: "db-1"}); ok {
+		t.Error("Rule() on empty config matched")
+	}
+}
@@ -0,0 +1,70 @@
+package domain
+
+import "time"
+
+// Diagnostic operations accepted in DiagnosticRequest.Operations.
+const (
+	DiagnosticTraceroute = "traceroute"
+	DiagnosticMTU        = "mtu"
+	DiagnosticDNS        = "dns"
+)
+
+// DiagnosticRequest asks the pinger to run network diagnostics against a
+// target. An empty Operations list runs all of them.
+type DiagnosticRequest struct {
+	ContainerID string   `json:"container_id"`
+	Operations  []string `json:"operations,omitempty"`
+}
+
+// DiagnosticResponse carries the results of a DiagnosticRequest or an error.
+// Error is ProbeErrorNotFound when the pinger does not monitor the target.
+type DiagnosticResponse struct {
+	ContainerID string             `json:"container_id"`
+	Identity    string             `json:"identity,omitempty"`
+	Results     []DiagnosticResult `json:"results"`
+	Error       string             `json:"error,omitempty"`
+}
+
+// DiagnosticNotApplicable prefixes DiagnosticResult.Error when an operation
+// cannot be run against the target at all, as opposed to having failed.
+const DiagnosticNotApplicable = "not applicable"
+
+// DiagnosticResult is the outcome of one operation against one address or
+// name. Details holds Traceroute, PathMTU or DNSLookup.
+type DiagnosticResult struct {
+	Operation string      `json:"operation"`
+	Target    string      `json:"target"`
+	Network   string      `json:"network,omitempty"`
+	Status    bool        `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Traceroute lists the hops towards a target. RTT is in seconds; a hop that
+// did not answer has an empty Address.
+type Traceroute struct {
+	Hops    []TracerouteHop `json:"hops"`
+	Reached bool            `json:"reached"`
+}
+
+// TracerouteHop is a single hop of a Traceroute.
+type TracerouteHop struct {
+	TTL     int     `json:"ttl"`
+	Address string  `json:"address,omitempty"`
+	RTT     float64 `json:"rtt,omitempty"`
+}
+
+// PathMTU is the largest packet that reaches the target unfragmented.
+type PathMTU struct {
+	MTU int `json:"mtu"`
+}
+
+// DNSLookup is the resolution of a container name on Docker's embedded DNS.
+// Expected holds the container's own addresses; Status requires an overlap.
+type DNSLookup struct {
+	Server    string   `json:"server"`
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses,omitempty"`
+	Expected  []string `json:"expected,omitempty"`
+}
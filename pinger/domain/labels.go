@@ -0,0 +1,32 @@
+package domain
+
+import "strings"
+
+// sensitiveSuffixes mark labels that may carry credentials. The .secret
+// labels only name an entry of the secrets file and stay public.
+var sensitiveSuffixes = []string{".password", ".token"}
+
+// PublicLabels returns a copy of labels without the ones that may carry
+// credentials, so that they never leave the pinger with published metadata.
+func PublicLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	public := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if !sensitiveLabel(key) {
+			public[key] = value
+		}
+	}
+	return public
+}
+
+func sensitiveLabel(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+package domain
+
+import "encoding/json"
+
+// OutboxMessage is a message that could not be published and waits on disk for replay.
+type OutboxMessage struct {
+	RoutingKey string          `json:"routing_key"`
+	Body       json.RawMessage `json:"body"`
+}
@@ -4,14 +4,288 @@ import "time"
 
 // PingResult represents the result of a ping operation.
 type PingResult struct {
+	ContainerID string    `json:"container_id"`
+	Host        string    `json:"host,omitempty"`
 	IP          string    `json:"ip"`
+	Network     string    `json:"network"`
+	Family      string    `json:"family"`
+	Timestamp   time.Time `json:"timestamp"`
 	PingTime    float64   `json:"ping_time"`
+	Status      bool      `json:"status"`
 	LastSuccess time.Time `json:"last_success"`
+	// Reason explains a failure that is not a plain probe failure, see ReasonNoSharedNetwork.
+	Reason string `json:"reason,omitempty"`
+	// EnvironmentHealthy tells whether the pinger reached its reference targets
+	// during the sweep; nil when no references are configured.
+	EnvironmentHealthy *bool `json:"environment_healthy,omitempty"`
+	// Mode tells whether the probe ran from the pinger (external) or inside the target's network namespace.
+	Mode string `json:"mode"`
+	// Netns holds in-container check details when Mode is ModeNetns.
+	Netns *NetnsResult `json:"netns,omitempty"`
+	// Probes holds results of all probes selected for the container at IP.
+	Probes []ProbeResult `json:"probes,omitempty"`
+	// Container carries metadata of the probed container.
+	Container *ContainerMeta `json:"container,omitempty"`
 }
 
-// Container represents a Docker container with its IP address.
+// Reasons reported in PingResult.Reason. ReasonNoSharedNetwork means the
+// pinger is not attached to the target's network, so the target could not be
+// probed at all; ReasonLatencyThreshold means the target answered slower than
+// its monitoring rule allows.
+const (
+	ReasonNoSharedNetwork  = "no_shared_network"
+	ReasonLatencyThreshold = "latency_threshold"
+)
+
+// Probe modes reported in PingResult.Mode.
+const (
+	ModeExternal = "external"
+	ModeNetns    = "netns"
+)
+
+// NetnsResult describes connectivity checks performed inside a container's network namespace.
+type NetnsResult struct {
+	Loopback  bool           `json:"loopback"`
+	Addresses []AddressCheck `json:"addresses,omitempty"`
+	Ports     []PortCheck    `json:"ports,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// AddressCheck is the result of pinging one of the container's own addresses from inside.
+type AddressCheck struct {
+	IP     string `json:"ip"`
+	Status bool   `json:"status"`
+}
+
+// PortCheck is the result of connecting to a container port.
+type PortCheck struct {
+	Port     int     `json:"port"`
+	Protocol string  `json:"protocol"`
+	Status   bool    `json:"status"`
+	Latency  float64 `json:"latency"`
+	Failure  string  `json:"failure,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// ProbeResult is the common envelope of every probe type. Details holds the
+// probe-specific payload such as PortCheck or HTTPCheck.
+type ProbeResult struct {
+	Type    string      `json:"probe_type"`
+	Target  string      `json:"target"`
+	Status  bool        `json:"status"`
+	Latency float64     `json:"latency"`
+	Failure string      `json:"failure,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// HTTPCheck is the result of an HTTP(S) health check. Timings are in seconds.
+type HTTPCheck struct {
+	URL        string     `json:"url"`
+	Status     bool       `json:"status"`
+	StatusCode int        `json:"status_code,omitempty"`
+	DNS        float64    `json:"dns"`
+	Connect    float64    `json:"connect"`
+	TLS        float64    `json:"tls"`
+	TTFB       float64    `json:"ttfb"`
+	Total      float64    `json:"total"`
+	CertExpiry *time.Time `json:"cert_expiry,omitempty"`
+	Failure    string     `json:"failure,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// GRPCCheck is the result of a grpc.health.v1.Health/Check call.
+// ServingStatus is SERVING, NOT_SERVING, SERVICE_UNKNOWN or UNKNOWN.
+type GRPCCheck struct {
+	Target        string  `json:"target"`
+	Service       string  `json:"service"`
+	Status        bool    `json:"status"`
+	ServingStatus string  `json:"serving_status"`
+	Latency       float64 `json:"latency"`
+	Failure       string  `json:"failure,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// DatastoreCheck is the result of a protocol handshake with a datastore.
+// Kind is postgres, redis or amqp.
+type DatastoreCheck struct {
+	Kind    string  `json:"kind"`
+	Target  string  `json:"target"`
+	Status  bool    `json:"status"`
+	Latency float64 `json:"latency"`
+	Failure string  `json:"failure,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// ScriptCheck describes a run of a user-provided Starlark check script.
+type ScriptCheck struct {
+	Script string `json:"script"`
+	Output string `json:"output,omitempty"`
+	Steps  uint64 `json:"steps"`
+}
+
+// Failure modes reported in probe results.
+const (
+	FailureRefused            = "refused"
+	FailureTimeout            = "timeout"
+	FailureUnreachable        = "unreachable"
+	FailureUnexpectedResponse = "unexpected_response"
+	FailureError              = "error"
+	FailureScript             = "script_failed"
+)
+
+// Container represents a Docker container with its network attachments.
+// IP holds the primary address used when a single address is needed.
 type Container struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-	IP   string `json:"ip"`
+	ContainerMeta
+	IP       string              `json:"ip"`
+	Networks []NetworkAttachment `json:"networks"`
+	Ports    []Port              `json:"ports,omitempty"`
+	// PID is the host PID of the container's init process, used to enter its namespaces.
+	PID int `json:"pid,omitempty"`
+}
+
+// Port is a port exposed by a container, optionally published on the host.
+type Port struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	HostPort int    `json:"host_port,omitempty"`
+}
+
+// ContainerMeta holds descriptive metadata of a container published with every result.
+// Identity is the logical identity that survives recreation, see Identity.
+// Source tells which target source discovered the container and Host which
+// Docker daemon runs it.
+type ContainerMeta struct {
+	ID             string            `json:"id"`
+	Identity       string            `json:"identity"`
+	Source         string            `json:"source"`
+	Host           string            `json:"host,omitempty"`
+	Name           string            `json:"name"`
+	Image          string            `json:"image"`
+	ImageDigest    string            `json:"image_digest"`
+	ComposeProject string            `json:"compose_project,omitempty"`
+	ComposeService string            `json:"compose_service,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	State          string            `json:"state"`
+	ExitCode       int               `json:"exit_code"`
+	RestartCount   int               `json:"restart_count"`
+	OOMKilled      bool              `json:"oom_killed"`
+	// Health is nil when the image defines no HEALTHCHECK.
+	Health *Health `json:"health,omitempty"`
+	// Swarm is set for Swarm tasks discovered through the services API.
+	Swarm *SwarmTask `json:"swarm,omitempty"`
+}
+
+// SwarmTask describes the Swarm task behind a target. DesiredTasks is the
+// number of tasks the service should run.
+type SwarmTask struct {
+	ServiceID    string `json:"service_id"`
+	ServiceName  string `json:"service_name"`
+	TaskID       string `json:"task_id"`
+	Slot         int    `json:"slot,omitempty"`
+	NodeID       string `json:"node_id"`
+	DesiredState string `json:"desired_state"`
+	DesiredTasks int    `json:"desired_tasks"`
+	Message      string `json:"message,omitempty"`
+}
+
+// ServiceHealth is the reachability of a Swarm service: Reachable of
+// Desired tasks answered their last probe.
+type ServiceHealth struct {
+	ServiceID   string    `json:"service_id"`
+	ServiceName string    `json:"service_name"`
+	Desired     int       `json:"desired"`
+	Running     int       `json:"running"`
+	Reachable   int       `json:"reachable"`
+	Status      bool      `json:"status"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Health is the Docker HEALTHCHECK state of a container.
+type Health struct {
+	Status        string    `json:"status"`
+	FailingStreak int       `json:"failing_streak"`
+	LastOutput    string    `json:"last_output,omitempty"`
+	LastExitCode  int       `json:"last_exit_code"`
+	LastCheck     time.Time `json:"last_check,omitempty"`
+}
+
+// StateRunning is the Docker state of a running container.
+const StateRunning = "running"
+
+// Target sources reported in ContainerMeta.Source.
+const (
+	SourceDocker = "docker"
+	SourceFile   = "file"
+	SourceSwarm  = "swarm"
+)
+
+// Docker Compose labels used to fill ContainerMeta.
+const (
+	LabelComposeProject = "com.docker.compose.project"
+	LabelComposeService = "com.docker.compose.service"
+	LabelComposeNumber  = "com.docker.compose.container-number"
+)
+
+// LabelIdentity overrides the logical identity of a container.
+const LabelIdentity = "monitor.identity"
+
+// DefaultHost names the Docker daemon taken from the environment when no
+// other name is configured.
+const DefaultHost = "local"
+
+// Identity returns the logical identity of a container: the monitor.identity
+// label, else the Compose project, service and replica number, else the name.
+// Derived identities are scoped to the Docker host, since the same project may
+// run on several hosts. Identities on DefaultHost stay unprefixed, as they were
+// before hosts were introduced, so existing history keeps matching. Unlike the
+// container ID the identity stays the same when the container is recreated.
+func Identity(host, name string, labels map[string]string) string {
+	if identity := labels[LabelIdentity]; identity != "" {
+		return identity
+	}
+	identity := "name:" + name
+	project, service := labels[LabelComposeProject], labels[LabelComposeService]
+	if project != "" && service != "" {
+		number := labels[LabelComposeNumber]
+		if number == "" {
+			number = "1"
+		}
+		identity = "compose:" + project + "/" + service + "/" + number
+	}
+	if host != "" && host != DefaultHost {
+		identity = host + "/" + identity
+	}
+	return identity
+}
+
+// NetworkAttachment describes a container's connection to a single Docker network.
+type NetworkAttachment struct {
+	Name    string   `json:"name"`
+	IPv4    string   `json:"ipv4,omitempty"`
+	IPv6    string   `json:"ipv6,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Address families reported in PingResult.Family.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// ContainerEvent represents a Docker lifecycle event of a container.
+// ExitCode is set for die events, Signal for kill events and HealthStatus
+// for health_status events.
+type ContainerEvent struct {
+	ContainerID  string    `json:"container_id"`
+	Host         string    `json:"host,omitempty"`
+	Action       string    `json:"action"`
+	Name         string    `json:"name,omitempty"`
+	Image        string    `json:"image,omitempty"`
+	Network      string    `json:"network,omitempty"`
+	ExitCode     *int      `json:"exit_code,omitempty"`
+	Signal       string    `json:"signal,omitempty"`
+	HealthStatus string    `json:"health_status,omitempty"`
+	Time         time.Time `json:"time"`
 }
@@ -0,0 +1,34 @@
+package domain
+
+import "testing"
+
+func TestIdentity(t *testing.T) {
+	compose := map[string]string{
+		LabelComposeProject: "shop",
+		LabelComposeService: "web",
+		LabelComposeNumber:  "2",
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		labels map[string]string
+		want   string
+	}{
+		{"name on default host", DefaultHost, nil, "name:web-1"},
+		{"name without host", "", nil, "name:web-1"},
+		{"name on other host", "edge", nil, "edge/name:web-1"},
+		{"compose on default host", DefaultHost, compose, "compose:shop/web/2"},
+		{"compose on other host", "edge", compose, "edge/compose:shop/web/2"},
+		{"compose without number", "", map[string]string{LabelComposeProject: "shop", LabelComposeService: "web"}, "compose:shop/web/1"},
+		{"label overrides host", "edge", map[string]string{LabelIdentity: "shop/web"}, "shop/web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Identity(tt.host, "web-1", tt.labels); got != tt.want {
+				t.Errorf("Identity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
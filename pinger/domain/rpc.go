@@ -0,0 +1,16 @@
+package domain
+
+// ProbeRequest asks the pinger to probe a target out of schedule.
+type ProbeRequest struct {
+	ContainerID string `json:"container_id"`
+}
+
+// ProbeResponse carries the fresh results of a ProbeRequest, one per probed
+// address, or an error.
+type ProbeResponse struct {
+	Results []PingResult `json:"results"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// ProbeErrorNotFound means the pinger does not monitor the requested target.
+const ProbeErrorNotFound = "container_not_found"
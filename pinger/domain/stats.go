@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// ContainerStats is a resource usage sample taken from the Docker stats API.
+// CPUPercent is relative to a single CPU, so it may exceed 100 on multi-core hosts.
+type ContainerStats struct {
+	ContainerID   string    `json:"container_id"`
+	Host          string    `json:"host,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	OnlineCPUs    uint32    `json:"online_cpus"`
+	MemoryUsage   uint64    `json:"memory_usage"`
+	MemoryLimit   uint64    `json:"memory_limit"`
+	MemoryPercent float64   `json:"memory_percent"`
+	NetworkRx     uint64    `json:"network_rx"`
+	NetworkTx     uint64    `json:"network_tx"`
+	BlockRead     uint64    `json:"block_read"`
+	BlockWrite    uint64    `json:"block_write"`
+	PIDs          uint64    `json:"pids"`
+}
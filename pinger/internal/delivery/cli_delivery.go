@@ -3,18 +3,22 @@ package delivery
 import (
 	"context"
 	"log"
-	"time"
 
 	"pinger/internal/repository"
 	"pinger/pinger"
+	"pinger/probe"
 )
 
-func RunCLI(ctx context.Context, dockerRepo repository.DockerRepository, rabbitRepo repository.RabbitMQRepository, interval time.Duration) {
+func RunCLI(ctx context.Context, dockerRepos []repository.DockerRepository, rabbitRepo repository.RabbitMQRepository, outboxRepo repository.OutboxRepository, configRepo repository.ConfigRepository, cfg pinger.Config, registry *probe.Registry, sources ...repository.TargetRepository) {
 	// Инициализация сервиса
-	pingerService := pinger.NewPingerService(dockerRepo, rabbitRepo)
+	pingerService := pinger.NewPingerService(dockerRepos, rabbitRepo, outboxRepo, configRepo, cfg, registry, sources...)
 
 	// Запуск сервиса
-	pingerService.Start(ctx, interval)
+	pingerService.Start(ctx)
 
-	log.Printf("Pinger service started with interval %v", interval)
+	hosts := make([]string, 0, len(dockerRepos))
+	for _, repo := range dockerRepos {
+		hosts = append(hosts, repo.Host())
+	}
+	log.Printf("Pinger service started with interval %v, %d workers, probes %v, Docker hosts %v", cfg.Interval, cfg.Workers, registry.Names(), hosts)
 }
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+	"pinger/domain"
+)
+
+// ConfigRepository отдаёт конфигурацию мониторинга, которой владеет бэкенд.
+type ConfigRepository interface {
+	// GetConfig забирает конфигурацию у бэкенда, а при его недоступности —
+	// последнюю удачную из кэша.
+	GetConfig(ctx context.Context) (domain.MonitorConfig, error)
+	// Changes сообщает, что конфигурация могла измениться.
+	Changes(ctx context.Context) <-chan struct{}
+}
+
+// configExchange — fanout-обменник, в который бэкенд публикует уведомления
+// об изменении конфигурации.
+const configExchange = "monitor_config"
+
+type configRepository struct {
+	url         string
+	token       string
+	cachePath   string
+	rabbitMQURL string
+	interval    time.Duration
+	client      *http.Client
+}
+
+// NewConfigRepository читает конфигурацию с backendURL/monitor/config и
+// сохраняет её в cachePath. Об изменениях сообщают уведомления из RabbitMQ,
+// а на случай их потери конфигурация перечитывается раз в interval.
+func NewConfigRepository(backendURL, token, cachePath, rabbitMQURL string, interval time.Duration) ConfigRepository {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &configRepository{
+		url:         strings.TrimRight(backendURL, "/") + "/monitor/config",
+		token:       token,
+		cachePath:   cachePath,
+		rabbitMQURL: rabbitMQURL,
+		interval:    interval,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (r *configRepository) GetConfig(ctx context.Context) (domain.MonitorConfig, error) {
+	config, err := r.fetch(ctx)
+	if err == nil {
+		if err := r.save(config); err != nil {
+			log.Printf("Failed to cache monitor config: %v", err)
+		}
+		return config, nil
+	}
+
+	cached, cacheErr := r.load()
+	if cacheErr != nil {
+		return domain.MonitorConfig{}, fmt.Errorf("fetch monitor config: %w; no cached config: %v", err, cacheErr)
+	}
+	log.Printf("Backend unavailable, using cached monitor config version %d: %v", cached.Version, err)
+	return cached, nil
+}
+
+func (r *configRepository) fetch(ctx context.Context) (domain.MonitorConfig, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return domain.MonitorConfig{}, err
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return domain.MonitorConfig{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return domain.MonitorConfig{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var config domain.MonitorConfig
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return domain.MonitorConfig{}, fmt.Errorf("decode monitor config: %w", err)
+	}
+	return config, nil
+}
+
+// save атомарно заменяет кэш, чтобы обрыв записи не испортил прошлую копию.
+func (r *configRepository) save(config domain.MonitorConfig) error {
+	if r.cachePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.cachePath), 0o755); err != nil {
+		return err
+	}
+	tmp := r.cachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.cachePath)
+}
+
+func (r *configRepository) load() (domain.MonitorConfig, error) {
+	if r.cachePath == "" {
+		return domain.MonitorConfig{}, os.ErrNotExist
+	}
+	data, err := os.ReadFile(r.cachePath)
+	if err != nil {
+		return domain.MonitorConfig{}, err
+	}
+	var config domain.MonitorConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return domain.MonitorConfig{}, fmt.Errorf("parse %s: %w", r.cachePath, err)
+	}
+	return config, nil
+}
+
+func (r *configRepository) Changes(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				notify()
+			}
+		}
+	}()
+
+	// Канал не закрывается: в него пишут две горутины
+	go func() {
+		for {
+			err := r.subscribe(ctx, notify)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Monitor config notifications interrupted: %v", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			// За время обрыва могли быть пропущены уведомления
+			notify()
+		}
+	}()
+
+	return changes
+}
+
+// subscribe привязывает к обменнику временную очередь и ждёт уведомлений,
+// пока не оборвётся соединение.
+func (r *configRepository) subscribe(ctx context.Context, notify func()) error {
+	conn, err := amqp.Dial(r.rabbitMQURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	err = ch.ExchangeDeclare(
+		configExchange, // name
+		"fanout",       // kind
+		true,           // durable
+		false,          // auto-deleted
+		false,          // internal
+		false,          // no-wait
+		nil,            // arguments
+	)
+	if err != nil {
+		return err
+	}
+	queue, err := ch.QueueDeclare(
+		"",    // name
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return err
+	}
+	if err := ch.QueueBind(queue.Name, "", configExchange, false, nil); err != nil {
+		return err
+	}
+	msgs, err := ch.Consume(
+		queue.Name, // queue
+		"",         // consumer
+		true,       // auto-ack
+		true,       // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
+	)
+	if err != nil {
+		return err
+	}
+	log.Println("Subscribed to monitor config notifications")
+
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-closed:
+			return err
+		case _, ok := <-msgs:
+			if !ok {
+				return fmt.Errorf("notification channel closed")
+			}
+			notify()
+		}
+	}
+}
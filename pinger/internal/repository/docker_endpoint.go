@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"pinger/domain"
+)
+
+// DockerEndpoint описывает подключение к одному демону Docker.
+type DockerEndpoint struct {
+	// Name — имя хоста, которым помечаются все результаты и события.
+	Name string
+	// URL — адрес демона: unix://, tcp:// или ssh://user@host[:port].
+	// Пустой адрес берётся из окружения (DOCKER_HOST и т.п.).
+	URL string
+	// TLSDir — каталог с ca.pem, cert.pem и key.pem для tcp://.
+	TLSDir string
+}
+
+// Local сообщает, что демон работает на той же машине, что и пингер, и его
+// PID-ы и сетевые пространства имён доступны локально.
+func (e DockerEndpoint) Local() bool {
+	if e.URL == "" {
+		host := os.Getenv("DOCKER_HOST")
+		return host == "" || strings.HasPrefix(host, "unix://")
+	}
+	return strings.HasPrefix(e.URL, "unix://")
+}
+
+// ParseDockerEndpoints разбирает список вида
+//
+//	local=unix:///var/run/docker.sock,edge=tcp://10.0.0.5:2376?tls=/certs/edge,build=ssh://deploy@build1
+//
+// Имя можно опустить, тогда им станет хост из адреса. Пустой список
+// означает один демон из окружения. Демон без имени и хоста в адресе
+// называется defaultName, а если оно пустое — local: имя должно переживать
+// пересоздание контейнера пингера, иначе сменятся идентичности целей.
+func ParseDockerEndpoints(spec, defaultName string) ([]DockerEndpoint, error) {
+	if defaultName == "" {
+		defaultName = domain.DefaultHost
+	}
+	var endpoints []DockerEndpoint
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var endpoint DockerEndpoint
+		if name, rawURL, ok := strings.Cut(item, "="); ok && !strings.Contains(name, "://") {
+			endpoint.Name, item = name, rawURL
+		}
+		u, err := url.Parse(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Docker endpoint %q: %w", item, err)
+		}
+		switch u.Scheme {
+		case "unix", "tcp", "ssh":
+		default:
+			return nil, fmt.Errorf("unsupported Docker endpoint scheme %q", u.Scheme)
+		}
+		endpoint.TLSDir = u.Query().Get("tls")
+		u.RawQuery = ""
+		endpoint.URL = u.String()
+		if endpoint.Name == "" {
+			endpoint.Name = u.Hostname()
+			if endpoint.Name == "" {
+				endpoint.Name = defaultName
+			}
+		}
+		if seen[endpoint.Name] {
+			return nil, errors.New("duplicate Docker endpoint name " + endpoint.Name)
+		}
+		seen[endpoint.Name] = true
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = append(endpoints, DockerEndpoint{Name: defaultName})
+	}
+	return endpoints, nil
+}
+
+// clientOptions возвращает параметры клиента Docker для подключения.
+func (e DockerEndpoint) clientOptions() ([]client.Opt, error) {
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	if e.URL == "" {
+		return append(opts, client.FromEnv), nil
+	}
+
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ssh":
+		// Как docker CLI: HTTP поверх stdin/stdout удалённого docker system dial-stdio
+		opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dialSSH(u)))
+	case "tcp":
+		opts = append(opts, client.WithHost(e.URL))
+		if e.TLSDir != "" {
+			opts = append(opts, client.WithTLSClientConfig(
+				filepath.Join(e.TLSDir, "ca.pem"),
+				filepath.Join(e.TLSDir, "cert.pem"),
+				filepath.Join(e.TLSDir, "key.pem"),
+			))
+		}
+	default:
+		opts = append(opts, client.WithHost(e.URL))
+	}
+	return opts, nil
+}
+
+// dialSSH подключается к демону через ssh и docker system dial-stdio.
+// Ключи и known_hosts берутся из обычной конфигурации ssh.
+func dialSSH(target *url.URL) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	args := []string{"-o", "BatchMode=yes"}
+	if target.User != nil {
+		args = append(args, "-l", target.User.Username())
+	}
+	if port := target.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, "--", target.Hostname(), "docker", "system", "dial-stdio")
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// Не CommandContext: ctx ограничивает только установку соединения
+		cmd := exec.Command("ssh", args...)
+		cmd.Stderr = io.Discard
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &commandConn{cmd: cmd, stdin: stdin, stdout: stdout, remote: target.Host}, nil
+	}
+}
+
+// commandConn — net.Conn поверх stdin/stdout дочернего процесса.
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	remote string
+}
+
+func (c *commandConn) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *commandConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+func (c *commandConn) Close() error {
+	c.stdin.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr  { return commandAddr("ssh") }
+func (c *commandConn) RemoteAddr() net.Addr { return commandAddr(c.remote) }
+
+// Дедлайны не поддерживаются: HTTP-клиент ограничивает запросы контекстом.
+func (c *commandConn) SetDeadline(time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(time.Time) error { return nil }
+
+type commandAddr string
+
+func (a commandAddr) Network() string { return "ssh" }
+func (a commandAddr) String() string  { return string(a) }
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDockerEndpoints(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		defaultName string
+		want        []DockerEndpoint
+		wantErr     bool
+	}{
+		{
+			name: "empty spec uses local",
+			spec: "",
+			want: []DockerEndpoint{{Name: "local"}},
+		},
+		{
+			name:        "empty spec uses default name",
+			spec:        " , ",
+			defaultName: "node1",
+			want:        []DockerEndpoint{{Name: "node1"}},
+		},
+		{
+			name: "named endpoints",
+			spec: "local=unix:///var/run/docker.sock, build=ssh://deploy@build1:2222",
+			want: []DockerEndpoint{
+				{Name: "local", URL: "unix:///var/run/docker.sock"},
+				{Name: "build", URL: "ssh://deploy@build1:2222"},
+			},
+		},
+		{
+			name: "tls directory in query",
+			spec: "edge=tcp://10.0.0.5:2376?tls=/certs/edge",
+			want: []DockerEndpoint{{Name: "edge", URL: "tcp://10.0.0.5:2376", TLSDir: "/certs/edge"}},
+		},
+		{
+			name: "equals sign in url of unnamed endpoint",
+			spec: "tcp://10.0.0.5:2376?tls=/certs/a=b",
+			want: []DockerEndpoint{{Name: "10.0.0.5", URL: "tcp://10.0.0.5:2376", TLSDir: "/certs/a=b"}},
+		},
+		{
+			name:        "unnamed unix socket uses default name",
+			spec:        "unix:///var/run/docker.sock",
+			defaultName: "node1",
+			want:        []DockerEndpoint{{Name: "node1", URL: "unix:///var/run/docker.sock"}},
+		},
+		{
+			name:    "duplicate names",
+			spec:    "a=unix:///var/run/docker.sock,a=tcp://10.0.0.5:2375",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate names taken from host",
+			spec:    "tcp://10.0.0.5:2375,tcp://10.0.0.5:2376",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate of default name",
+			spec:    "unix:///var/run/docker.sock,local=tcp://10.0.0.5:2375",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			spec:    "web=http://10.0.0.5:2375",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDockerEndpoints(tt.spec, tt.defaultName)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseDockerEndpoints(%q) = %+v, want error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDockerEndpoints(%q) error: %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDockerEndpoints(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,73 +2,387 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"github.com/docker/docker/api/types/container"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
 	"pinger/domain"
 )
 
 type DockerRepository interface {
-	GetContainers() ([]domain.Container, error)
+	// Host — имя демона, которым помечаются его контейнеры.
+	Host() string
+	GetContainers(ctx context.Context) ([]domain.Container, error)
+	GetContainer(ctx context.Context, id string) (domain.Container, error)
+	Events(ctx context.Context) (<-chan domain.ContainerEvent, <-chan error)
+	Stats(ctx context.Context, id string) (domain.ContainerStats, error)
+	ConnectNetwork(ctx context.Context, network, containerID string) error
+	DisconnectNetwork(ctx context.Context, network, containerID string) error
 }
 
 type dockerRepository struct {
 	dockerClient *client.Client
+	endpoint     DockerEndpoint
 }
 
-func NewDockerRepository() (DockerRepository, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+func NewDockerRepository(endpoint DockerEndpoint) (DockerRepository, error) {
+	opts, err := endpoint.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, err
 	}
-	return &dockerRepository{dockerClient: cli}, nil
+	return &dockerRepository{dockerClient: cli, endpoint: endpoint}, nil
 }
 
-func (r *dockerRepository) GetContainers() ([]domain.Container, error) {
+func (r *dockerRepository) Host() string { return r.endpoint.Name }
+
+func (r *dockerRepository) GetContainers(ctx context.Context) ([]domain.Container, error) {
 	if r.dockerClient == nil {
 		return nil, errors.New("Docker client is not initialized")
 	}
 
-	ctx := context.Background()
-
 	// Получаем список контейнеров
-	containers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{})
+	// All: остановленные и упавшие контейнеры тоже должны попадать в мониторинг
+	containers, err := r.dockerClient.ContainerList(ctx, container.ListOptions{All: true})
 	if err != nil {
 		log.Printf("Error listing containers: %v", err)
 		return nil, err
 	}
 
 	var result []domain.Container
-	for _, container := range containers {
-		inspect, err := r.dockerClient.ContainerInspect(ctx, container.ID)
+	for _, c := range containers {
+		info, err := r.GetContainer(ctx, c.ID)
 		if err != nil {
-			log.Printf("Error inspecting container %s: %v", container.ID, err)
+			log.Printf("Error inspecting container %s: %v", c.ID, err)
 			continue
 		}
+		result = append(result, info)
+	}
+
+	return result, nil
+}
 
-		// Ищем первый доступный IP-адрес из всех сетей
-		var ip string
+func (r *dockerRepository) GetContainer(ctx context.Context, id string) (domain.Container, error) {
+	if r.dockerClient == nil {
+		return domain.Container{}, errors.New("Docker client is not initialized")
+	}
+
+	inspect, err := r.dockerClient.ContainerInspect(ctx, id)
+	if err != nil {
+		return domain.Container{}, err
+	}
+
+	// Собираем все подключения; сортируем сети, чтобы основной IP не зависел от порядка map
+	var networks []domain.NetworkAttachment
+	if inspect.NetworkSettings != nil {
 		for networkName, network := range inspect.NetworkSettings.Networks {
-			if network.IPAddress != "" {
-				ip = network.IPAddress
-				log.Printf("Container %s is connected to network %s with IP %s", container.ID, networkName, ip)
-				break
+			if network.IPAddress == "" && network.GlobalIPv6Address == "" {
+				continue
 			}
+			networks = append(networks, domain.NetworkAttachment{
+				Name:    networkName,
+				IPv4:    network.IPAddress,
+				IPv6:    network.GlobalIPv6Address,
+				Aliases: network.Aliases,
+			})
 		}
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
 
-		// Если IP-адрес не найден, пропускаем контейнер
+	// У остановленного контейнера адресов нет, но его состояние всё равно отслеживается
+	var ip string
+	if len(networks) > 0 {
+		ip = networks[0].IPv4
 		if ip == "" {
-			log.Printf("Container %s has no IP address in any network", container.ID)
-			continue
+			ip = networks[0].IPv6
 		}
+	}
 
-		result = append(result, domain.Container{
-			ID: container.ID,
-			IP: ip,
-		})
+	meta := domain.ContainerMeta{
+		ID:          inspect.ID,
+		Source:      domain.SourceDocker,
+		Host:        r.endpoint.Name,
+		Name:        strings.TrimPrefix(inspect.Name, "/"),
+		ImageDigest: inspect.Image,
+	}
+	if inspect.Config != nil {
+		meta.Image = inspect.Config.Image
+		meta.Labels = inspect.Config.Labels
+		meta.ComposeProject = inspect.Config.Labels[domain.LabelComposeProject]
+		meta.ComposeService = inspect.Config.Labels[domain.LabelComposeService]
+	}
+	if inspect.State != nil {
+		meta.State = inspect.State.Status
+		meta.ExitCode = inspect.State.ExitCode
+		meta.OOMKilled = inspect.State.OOMKilled
+		meta.Health = containerHealth(inspect.State.Health)
+	}
+	meta.RestartCount = inspect.RestartCount
+	meta.Identity = domain.Identity(meta.Host, meta.Name, meta.Labels)
+	// Предпочитаем digest из реестра, он одинаков на всех хостах
+	if image, _, err := r.dockerClient.ImageInspectWithRaw(ctx, inspect.Image); err == nil && len(image.RepoDigests) > 0 {
+		meta.ImageDigest = image.RepoDigests[0]
 	}
 
+	result := domain.Container{
+		ContainerMeta: meta,
+		IP:            ip,
+		Networks:      networks,
+		Ports:         containerPorts(inspect),
+	}
+	// PID имеет смысл только для демона на этой же машине
+	if inspect.State != nil && r.endpoint.Local() {
+		result.PID = inspect.State.Pid
+	}
 	return result, nil
 }
+
+// maxHealthOutput ограничивает размер вывода HEALTHCHECK в сообщениях.
+const maxHealthOutput = 1024
+
+func containerHealth(health *types.Health) *domain.Health {
+	if health == nil || health.Status == types.NoHealthcheck {
+		return nil
+	}
+	result := &domain.Health{
+		Status:        health.Status,
+		FailingStreak: health.FailingStreak,
+	}
+	// Log упорядочен от старых записей к новым
+	if n := len(health.Log); n > 0 && health.Log[n-1] != nil {
+		last := health.Log[n-1]
+		output := strings.TrimSpace(last.Output)
+		if len(output) > maxHealthOutput {
+			output = output[:maxHealthOutput]
+		}
+		result.LastOutput = output
+		result.LastExitCode = last.ExitCode
+		result.LastCheck = last.End
+	}
+	return result
+}
+
+// containerPorts объединяет EXPOSE-порты образа и опубликованные на хосте порты.
+func containerPorts(inspect types.ContainerJSON) []domain.Port {
+	byKey := make(map[nat.Port]*domain.Port)
+	add := func(p nat.Port) *domain.Port {
+		if port, ok := byKey[p]; ok {
+			return port
+		}
+		port := &domain.Port{Port: p.Int(), Protocol: p.Proto()}
+		byKey[p] = port
+		return port
+	}
+
+	if inspect.Config != nil {
+		for p := range inspect.Config.ExposedPorts {
+			add(p)
+		}
+	}
+	if inspect.NetworkSettings != nil {
+		for p, bindings := range inspect.NetworkSettings.Ports {
+			port := add(p)
+			for _, binding := range bindings {
+				if hostPort, err := strconv.Atoi(binding.HostPort); err == nil {
+					port.HostPort = hostPort
+					break
+				}
+			}
+		}
+	}
+
+	ports := make([]domain.Port, 0, len(byKey))
+	for _, port := range byKey {
+		ports = append(ports, *port)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Protocol < ports[j].Protocol
+	})
+	return ports
+}
+
+// IsNotFound сообщает, что контейнер уже удалён из Docker.
+func IsNotFound(err error) bool {
+	return errdefs.IsNotFound(err)
+}
+
+// Events подписывается на события жизненного цикла контейнеров и их сетей.
+func (r *dockerRepository) Events(ctx context.Context) (<-chan domain.ContainerEvent, <-chan error) {
+	args := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("type", string(events.NetworkEventType)),
+		filters.Arg("event", string(events.ActionCreate)),
+		filters.Arg("event", string(events.ActionStart)),
+		filters.Arg("event", string(events.ActionRestart)),
+		filters.Arg("event", string(events.ActionKill)),
+		filters.Arg("event", string(events.ActionOOM)),
+		filters.Arg("event", string(events.ActionDie)),
+		filters.Arg("event", string(events.ActionStop)),
+		filters.Arg("event", string(events.ActionDestroy)),
+		filters.Arg("event", string(events.ActionConnect)),
+		filters.Arg("event", string(events.ActionDisconnect)),
+		filters.Arg("event", string(events.ActionHealthStatus)),
+	)
+	messages, errs := r.dockerClient.Events(ctx, events.ListOptions{Filters: args})
+
+	out := make(chan domain.ContainerEvent)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				event := containerEvent(msg)
+				event.Host = r.endpoint.Name
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// containerEvent переводит сообщение Docker в событие контейнера. Метки
+// контейнера из атрибутов не переносятся — они есть в метаданных.
+func containerEvent(msg events.Message) domain.ContainerEvent {
+	attrs := msg.Actor.Attributes
+	event := domain.ContainerEvent{
+		ContainerID: msg.Actor.ID,
+		Action:      string(msg.Action),
+		Name:        attrs["name"],
+		Image:       attrs["image"],
+		Time:        time.Unix(0, msg.TimeNano),
+	}
+
+	// Для сетевых событий Actor — это сеть, а контейнер передаётся в атрибутах
+	if msg.Type == events.NetworkEventType {
+		event.ContainerID = attrs["container"]
+		event.Network = attrs["name"]
+		event.Name = ""
+		return event
+	}
+
+	// Docker передаёт статус в самом действии: "health_status: healthy"
+	if status, ok := strings.CutPrefix(event.Action, string(events.ActionHealthStatus)+":"); ok {
+		event.Action = string(events.ActionHealthStatus)
+		event.HealthStatus = strings.TrimSpace(status)
+	}
+	if code, err := strconv.Atoi(attrs["exitCode"]); err == nil {
+		event.ExitCode = &code
+	}
+	event.Signal = attrs["signal"]
+	return event
+}
+
+// Stats снимает одну выборку потребления ресурсов. Docker сам делает два
+// замера с интервалом около секунды, поэтому загрузка CPU считается по ним.
+func (r *dockerRepository) Stats(ctx context.Context, id string) (domain.ContainerStats, error) {
+	if r.dockerClient == nil {
+		return domain.ContainerStats{}, errors.New("Docker client is not initialized")
+	}
+
+	resp, err := r.dockerClient.ContainerStats(ctx, id, false)
+	if err != nil {
+		return domain.ContainerStats{}, err
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return domain.ContainerStats{}, err
+	}
+
+	stats := domain.ContainerStats{
+		ContainerID: id,
+		Host:        r.endpoint.Name,
+		Timestamp:   raw.Read,
+		CPUPercent:  cpuPercent(raw.CPUStats, raw.PreCPUStats),
+		OnlineCPUs:  raw.CPUStats.OnlineCPUs,
+		MemoryUsage: memoryUsage(raw.MemoryStats),
+		MemoryLimit: raw.MemoryStats.Limit,
+		PIDs:        raw.PidsStats.Current,
+	}
+	if stats.Timestamp.IsZero() {
+		stats.Timestamp = time.Now()
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+	for _, network := range raw.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+	return stats, nil
+}
+
+// cpuPercent считает загрузку так же, как docker stats.
+func cpuPercent(cur, prev container.CPUStats) float64 {
+	cpuDelta := float64(cur.CPUUsage.TotalUsage) - float64(prev.CPUUsage.TotalUsage)
+	systemDelta := float64(cur.SystemUsage) - float64(prev.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	cpus := float64(cur.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(cur.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * cpus * 100
+}
+
+// memoryUsage вычитает страничный кэш, как docker stats (cgroup v1 и v2).
+func memoryUsage(mem container.MemoryStats) uint64 {
+	cache, ok := mem.Stats["total_inactive_file"]
+	if !ok {
+		cache = mem.Stats["inactive_file"]
+	}
+	if cache < mem.Usage {
+		return mem.Usage - cache
+	}
+	return mem.Usage
+}
+
+func (r *dockerRepository) ConnectNetwork(ctx context.Context, network, containerID string) error {
+	return r.dockerClient.NetworkConnect(ctx, network, containerID, nil)
+}
+
+func (r *dockerRepository) DisconnectNetwork(ctx context.Context, network, containerID string) error {
+	err := r.dockerClient.NetworkDisconnect(ctx, network, containerID, false)
+	if errdefs.IsNotFound(err) {
+		// Сеть уже удалена вместе с последним контейнером
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"pinger/domain"
+	"pinger/probe"
+)
+
+// TargetRepository — дополнительный источник целей помимо Docker.
+type TargetRepository interface {
+	// Source — имя источника, которым помечаются его цели.
+	Source() string
+	GetTargets(ctx context.Context) ([]domain.Container, error)
+	// Changes сообщает, что цели источника могли измениться.
+	Changes(ctx context.Context) <-chan struct{}
+}
+
+// fileTarget — описание цели в файле.
+type fileTarget struct {
+	Name    string            `yaml:"name"`
+	Host    string            `yaml:"host"`
+	Network string            `yaml:"network"`
+	Probes  []string          `yaml:"probes"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+type fileTargets struct {
+	Targets []fileTarget `yaml:"targets"`
+}
+
+// defaultFileNetwork — имя сети для целей, у которых она не указана.
+const defaultFileNetwork = "static"
+
+type fileTargetRepository struct {
+	path     string
+	interval time.Duration
+}
+
+// NewFileTargetRepository читает цели из YAML- или JSON-файла path и
+// проверяет его изменение раз в interval.
+//
+//	targets:
+//	  - name: gateway
+//	    host: 10.0.0.1
+//	    network: lan
+//	    probes: [icmp, tcp]
+//	    labels:
+//	      monitor.tcp.ports: "22"
+func NewFileTargetRepository(path string, interval time.Duration) TargetRepository {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &fileTargetRepository{path: path, interval: interval}
+}
+
+func (r *fileTargetRepository) Source() string { return domain.SourceFile }
+
+func (r *fileTargetRepository) GetTargets(ctx context.Context) ([]domain.Container, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON — подмножество YAML, поэтому один разборщик подходит для обоих форматов
+	var file fileTargets
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", r.path, err)
+	}
+
+	seen := make(map[string]bool, len(file.Targets))
+	result := make([]domain.Container, 0, len(file.Targets))
+	for i, target := range file.Targets {
+		if target.Name == "" || target.Host == "" {
+			return nil, fmt.Errorf("target %d: name and host are required", i+1)
+		}
+		if seen[target.Name] {
+			return nil, errors.New("duplicate target " + target.Name)
+		}
+		seen[target.Name] = true
+		result = append(result, target.container())
+	}
+	return result, nil
+}
+
+func (t fileTarget) container() domain.Container {
+	labels := make(map[string]string, len(t.Labels)+1)
+	for k, v := range t.Labels {
+		labels[k] = v
+	}
+	if len(t.Probes) > 0 {
+		labels[probe.LabelProbes] = strings.Join(t.Probes, ",")
+	}
+
+	network := domain.NetworkAttachment{Name: t.Network}
+	if network.Name == "" {
+		network.Name = defaultFileNetwork
+	}
+	if probe.Family(t.Host) == domain.FamilyIPv6 {
+		network.IPv6 = t.Host
+	} else {
+		network.IPv4 = t.Host
+	}
+
+	id := domain.SourceFile + ":" + t.Name
+	return domain.Container{
+		ContainerMeta: domain.ContainerMeta{
+			ID:       id,
+			Identity: id,
+			Name:     t.Name,
+			Source:   domain.SourceFile,
+			Labels:   labels,
+			State:    domain.StateRunning,
+		},
+		IP:       t.Host,
+		Networks: []domain.NetworkAttachment{network},
+	}
+}
+
+// Changes опрашивает время изменения и размер файла: inotify теряет события
+// при атомарной замене файла, а примонтированные configmap-ы меняются именно так.
+func (r *fileTargetRepository) Changes(ctx context.Context) <-chan struct{} {
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		last := r.stat()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current := r.stat()
+			if current == last {
+				continue
+			}
+			last = current
+			select {
+			case out <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return out
+}
+
+// stat возвращает отпечаток файла; пустой, если файл недоступен.
+func (r *fileTargetRepository) stat() string {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
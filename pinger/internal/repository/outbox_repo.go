@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"pinger/domain"
+)
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+)
+
+// OutboxRepository хранит неотправленные сообщения на диске до восстановления брокера.
+type OutboxRepository interface {
+	Append(msg domain.OutboxMessage) error
+	Drain(send func(msg domain.OutboxMessage) error) error
+	Pending() bool
+}
+
+type outboxRepository struct {
+	mu           sync.Mutex
+	dir          string
+	segmentBytes int64
+	maxBytes     int64
+
+	// segments — номера сегментов по возрастанию, последний открыт на запись.
+	segments []uint64
+	sizes    map[uint64]int64
+	active   *os.File
+	// offset — позиция чтения в самом старом сегменте.
+	offset int64
+}
+
+// NewOutboxRepository открывает (или создаёт) очередь в каталоге dir.
+// segmentBytes ограничивает размер одного файла, maxBytes — общий объём очереди;
+// при переполнении удаляется самый старый сегмент.
+func NewOutboxRepository(dir string, segmentBytes, maxBytes int64) (OutboxRepository, error) {
+	if segmentBytes <= 0 || maxBytes < segmentBytes {
+		return nil, errors.New("invalid outbox size limits")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	repo := &outboxRepository{
+		dir:          dir,
+		segmentBytes: segmentBytes,
+		maxBytes:     maxBytes,
+		sizes:        make(map[uint64]int64),
+	}
+	if err := repo.load(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *outboxRepository) load() error {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			log.Printf("Skipping unknown outbox file %s", name)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		r.segments = append(r.segments, seq)
+		r.sizes[seq] = info.Size()
+	}
+	sort.Slice(r.segments, func(i, j int) bool { return r.segments[i] < r.segments[j] })
+
+	data, err := os.ReadFile(filepath.Join(r.dir, cursorFile))
+	if err == nil {
+		r.offset, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if len(r.segments) > 0 {
+		log.Printf("Outbox contains %d segment(s) pending replay", len(r.segments))
+	}
+	return nil
+}
+
+func (r *outboxRepository) segmentPath(seq uint64) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+func (r *outboxRepository) totalBytes() int64 {
+	var total int64
+	for _, size := range r.sizes {
+		total += size
+	}
+	return total
+}
+
+// openActive открывает последний сегмент на дозапись или создаёт новый.
+func (r *outboxRepository) openActive(need int64) error {
+	if len(r.segments) > 0 {
+		last := r.segments[len(r.segments)-1]
+		if r.sizes[last]+need <= r.segmentBytes || r.sizes[last] == 0 {
+			if r.active == nil {
+				f, err := os.OpenFile(r.segmentPath(last), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+				if err != nil {
+					return err
+				}
+				r.active = f
+			}
+			return nil
+		}
+	}
+
+	if r.active != nil {
+		r.active.Close()
+		r.active = nil
+	}
+	var seq uint64 = 1
+	if len(r.segments) > 0 {
+		seq = r.segments[len(r.segments)-1] + 1
+	}
+	f, err := os.OpenFile(r.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	r.active = f
+	r.segments = append(r.segments, seq)
+	r.sizes[seq] = 0
+	return nil
+}
+
+func (r *outboxRepository) Append(msg domain.OutboxMessage) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.openActive(int64(len(line))); err != nil {
+		return err
+	}
+
+	// Ограничиваем объём: теряем самые старые данные, но не текущий сегмент
+	for r.totalBytes()+int64(len(line)) > r.maxBytes && len(r.segments) > 1 {
+		oldest := r.segments[0]
+		log.Printf("Outbox is full, dropping segment %d (%d bytes)", oldest, r.sizes[oldest])
+		if err := r.removeOldest(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.active.Write(line); err != nil {
+		return err
+	}
+	if err := r.active.Sync(); err != nil {
+		return err
+	}
+	r.sizes[r.segments[len(r.segments)-1]] += int64(len(line))
+	return nil
+}
+
+func (r *outboxRepository) removeOldest() error {
+	oldest := r.segments[0]
+	if len(r.segments) == 1 && r.active != nil {
+		r.active.Close()
+		r.active = nil
+	}
+	if err := os.Remove(r.segmentPath(oldest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	r.segments = r.segments[1:]
+	delete(r.sizes, oldest)
+	return r.saveOffset(0)
+}
+
+func (r *outboxRepository) saveOffset(offset int64) error {
+	r.offset = offset
+	tmp := filepath.Join(r.dir, cursorFile+".tmp")
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(r.dir, cursorFile))
+}
+
+// Drain отправляет сообщения в порядке записи. При первой ошибке send
+// позиция сохраняется, и следующий вызов продолжит с того же сообщения.
+func (r *outboxRepository) Drain(send func(msg domain.OutboxMessage) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for len(r.segments) > 0 {
+		oldest := r.segments[0]
+		if err := r.drainSegment(oldest, send); err != nil {
+			return err
+		}
+		if err := r.removeOldest(); err != nil {
+			return err
+		}
+		log.Printf("Outbox segment %d replayed", oldest)
+	}
+	return nil
+}
+
+func (r *outboxRepository) drainSegment(seq uint64, send func(msg domain.OutboxMessage) error) error {
+	f, err := os.Open(r.segmentPath(seq))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(r.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	offset := r.offset
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Недописанная строка после аварийного завершения отбрасывается
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg domain.OutboxMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			log.Printf("Skipping corrupted outbox record in segment %d: %v", seq, err)
+		} else if err := send(msg); err != nil {
+			return err
+		}
+
+		offset += int64(len(line))
+		if err := r.saveOffset(offset); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *outboxRepository) Pending() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, seq := range r.segments {
+		if r.sizes[seq] > 0 {
+			return true
+		}
+	}
+	return false
+}
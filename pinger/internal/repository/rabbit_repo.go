@@ -1,40 +1,48 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/streadway/amqp"
 	"log"
+	"sync"
 )
 
 type RabbitMQRepository interface {
 	PublishPingResult(result interface{}) error
+	Publish(routingKey string, body []byte) error
+	// ServeRPC отвечает на запросы из очереди queue, пока не оборвётся
+	// соединение или не истечёт ctx. Ответ уходит в reply_to запроса, а сам
+	// запрос подтверждается сразу после ответа.
+	ServeRPC(ctx context.Context, queue string, handle func(ctx context.Context, body []byte) (reply []byte)) error
 	Close() error
 }
 
 type rabbitMQRepository struct {
+	// mu защищает conn и ch: публикуют несколько горутин, а переподключение их заменяет
+	mu          sync.Mutex
 	conn        *amqp.Connection
 	ch          *amqp.Channel
 	rabbitMQURL string
 }
 
-func NewRabbitMQRepository(rabbitMQURL string) (RabbitMQRepository, error) {
+// NewRabbitMQRepository не требует доступного брокера: если подключиться
+// сразу не удалось, соединение устанавливается при следующей отправке, а до
+// тех пор Publish возвращает ошибку и сообщения копятся в outbox.
+func NewRabbitMQRepository(rabbitMQURL string) RabbitMQRepository {
 	repo := &rabbitMQRepository{
 		rabbitMQURL: rabbitMQURL,
 	}
 
-	// Попытка подключения к RabbitMQ
-	if err := repo.connect(); err != nil {
-		return nil, err
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := repo.ensureConnection(); err != nil {
+		log.Printf("RabbitMQ is not available yet, will retry on publish: %v", err)
 	}
 
-	// Объявление очереди
-	if err := repo.declareQueue(); err != nil {
-		repo.Close()
-		return nil, err
-	}
-
-	return repo, nil
+	return repo
 }
 
 func (r *rabbitMQRepository) connect() error {
@@ -44,7 +52,7 @@ func (r *rabbitMQRepository) connect() error {
 	conn, err = amqp.Dial(r.rabbitMQURL)
 
 	if err != nil {
-		return errors.New("failed to connect to RabbitMQ after multiple attempts")
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
 	ch, err := conn.Channel()
@@ -59,25 +67,30 @@ func (r *rabbitMQRepository) connect() error {
 	return nil
 }
 
+// queues — очереди, в которые публикует пингер.
+var queues = []string{"ping_results", "container_events", "container_stats", "service_health"}
+
 func (r *rabbitMQRepository) declareQueue() error {
 	if r.ch == nil {
 		return errors.New("RabbitMQ channel is not initialized")
 	}
 
-	_, err := r.ch.QueueDeclare(
-		"ping_results", // name
-		true,           // durable
-		false,          // delete when unused
-		false,          // exclusive
-		false,          // no-wait
-		nil,            // arguments
-	)
-	if err != nil {
-		log.Printf("Failed to declare queue: %v", err)
-		return err
-	}
+	for _, name := range queues {
+		_, err := r.ch.QueueDeclare(
+			name,  // name
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		if err != nil {
+			log.Printf("Failed to declare queue: %v", err)
+			return err
+		}
 
-	log.Println("Queue 'ping_results' declared successfully")
+		log.Printf("Queue '%s' declared successfully", name)
+	}
 	return nil
 }
 
@@ -88,43 +101,144 @@ func (r *rabbitMQRepository) ensureConnection() error {
 			log.Printf("Failed to reconnect to RabbitMQ: %v", err)
 			return err
 		}
+		if err := r.declareQueue(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 func (r *rabbitMQRepository) PublishPingResult(result interface{}) error {
-	// Убедимся, что соединение активно
-	if err := r.ensureConnection(); err != nil {
-		return err
-	}
-
 	body, err := json.Marshal(result)
 	if err != nil {
 		log.Printf("Failed to marshal result: %v", err)
 		return err
 	}
 
-	err = r.ch.Publish(
-		"",             // exchange
-		"ping_results", // routing key
-		false,          // mandatory
-		false,          // immediate
+	return r.Publish("ping_results", body)
+}
+
+func (r *rabbitMQRepository) Publish(routingKey string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Убедимся, что соединение активно
+	if err := r.ensureConnection(); err != nil {
+		return err
+	}
+
+	err := r.ch.Publish(
+		"",         // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         body,
 		},
 	)
 
 	if err != nil {
 		log.Printf("Failed to publish message: %v", err)
+		// Канал после ошибки непригоден, переподключимся при следующей отправке
+		r.conn.Close()
 		return err
 	}
-
-	log.Println("Message published successfully")
 	return nil
 }
 
+// rpcPrefetch ограничивает число запросов, взятых пингером, но ещё не
+// выполненных: остальные достаются другим пингерам.
+const rpcPrefetch = 8
+
+func (r *rabbitMQRepository) ServeRPC(ctx context.Context, queue string, handle func(ctx context.Context, body []byte) []byte) error {
+	// Отдельное соединение, чтобы обрыв подписки не мешал публикации результатов
+	conn, err := amqp.Dial(r.rabbitMQURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	_, err = ch.QueueDeclare(
+		queue, // name
+		false, // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return err
+	}
+	if err := ch.Qos(rpcPrefetch, 0, false); err != nil {
+		return err
+	}
+	msgs, err := ch.Consume(
+		queue, // queue
+		"",    // consumer
+		false, // auto-ack
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("Serving requests from queue '%s'", queue)
+
+	// Запросы выполняются параллельно, а публикация в канал — по одному
+	var mu sync.Mutex
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-closed:
+			return err
+		case msg, ok := <-msgs:
+			if !ok {
+				return errors.New("request channel closed")
+			}
+			if msg.ReplyTo == "" {
+				log.Printf("Dropping request without reply_to from queue '%s'", queue)
+				msg.Ack(false)
+				continue
+			}
+			go func() {
+				reply := handle(ctx, msg.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				// Запрос подтверждается и без ответа: повтор вызывающему уже не нужен
+				defer msg.Ack(false)
+				err := ch.Publish(
+					"",          // exchange
+					msg.ReplyTo, // routing key
+					false,       // mandatory
+					false,       // immediate
+					amqp.Publishing{
+						ContentType:   "application/json",
+						CorrelationId: msg.CorrelationId,
+						Body:          reply,
+					},
+				)
+				if err != nil {
+					log.Printf("Failed to reply to request %s: %v", msg.CorrelationId, err)
+				}
+			}()
+		}
+	}
+}
+
 func (r *rabbitMQRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if r.ch != nil {
 		if err := r.ch.Close(); err != nil {
 			log.Printf("Failed to close RabbitMQ channel: %v", err)
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"pinger/domain"
+)
+
+// LabelStackNamespace — метка docker stack deploy с именем стека.
+const LabelStackNamespace = "com.docker.stack.namespace"
+
+type swarmRepository struct {
+	dockerClient *client.Client
+	interval     time.Duration
+}
+
+// NewSwarmRepository обнаруживает задачи сервисов Swarm через менеджер
+// endpoint и перечитывает их раз в interval. В отличие от списка
+// контейнеров видны задачи на всех узлах кластера.
+func NewSwarmRepository(endpoint DockerEndpoint, interval time.Duration) (TargetRepository, error) {
+	opts, err := endpoint.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &swarmRepository{dockerClient: cli, interval: interval}, nil
+}
+
+func (r *swarmRepository) Source() string { return domain.SourceSwarm }
+
+func (r *swarmRepository) GetTargets(ctx context.Context) ([]domain.Container, error) {
+	services, err := r.dockerClient.ServiceList(ctx, types.ServiceListOptions{Status: true})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]swarm.Service, len(services))
+	for _, service := range services {
+		byID[service.ID] = service
+	}
+
+	nodes, err := r.dockerClient.NodeList(ctx, types.NodeListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	hostnames := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		hostnames[node.ID] = node.Description.Hostname
+	}
+
+	// Только задачи, которые должны работать: завершённые и заменённые не интересны
+	tasks, err := r.dockerClient.TaskList(ctx, types.TaskListOptions{
+		Filters: filters.NewArgs(filters.Arg("desired-state", string(swarm.TaskStateRunning))),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.Container, 0, len(tasks))
+	for _, task := range tasks {
+		service, ok := byID[task.ServiceID]
+		if !ok {
+			continue
+		}
+		result = append(result, swarmTarget(service, task, hostnames[task.NodeID]))
+	}
+	return result, nil
+}
+
+func swarmTarget(service swarm.Service, task swarm.Task, host string) domain.Container {
+	labels := make(map[string]string)
+	for k, v := range service.Spec.Labels {
+		labels[k] = v
+	}
+	var image string
+	if spec := task.Spec.ContainerSpec; spec != nil {
+		image = spec.Image
+		for k, v := range spec.Labels {
+			labels[k] = v
+		}
+	}
+
+	// У глобальных сервисов нет номера реплики, задачу определяет узел
+	slot := strconv.Itoa(task.Slot)
+	if task.Slot == 0 {
+		slot = task.NodeID
+	}
+	name := service.Spec.Name + "." + slot
+
+	meta := domain.ContainerMeta{
+		ID:             "task:" + task.ID,
+		Identity:       "swarm:" + service.Spec.Name + "/" + slot,
+		Source:         domain.SourceSwarm,
+		Host:           host,
+		Name:           name,
+		Image:          image,
+		ComposeProject: labels[LabelStackNamespace],
+		ComposeService: service.Spec.Name,
+		Labels:         labels,
+		State:          string(task.Status.State),
+		Swarm: &domain.SwarmTask{
+			ServiceID:    service.ID,
+			ServiceName:  service.Spec.Name,
+			TaskID:       task.ID,
+			Slot:         task.Slot,
+			NodeID:       task.NodeID,
+			DesiredState: string(task.DesiredState),
+			Message:      task.Status.Err,
+		},
+	}
+	if service.ServiceStatus != nil {
+		meta.Swarm.DesiredTasks = int(service.ServiceStatus.DesiredTasks)
+	}
+	if status := task.Status.ContainerStatus; status != nil {
+		meta.ExitCode = status.ExitCode
+	}
+
+	var networks []domain.NetworkAttachment
+	for _, attachment := range task.NetworksAttachments {
+		// Ingress-сеть обслуживает публикацию портов, адреса в ней не принадлежат сервису
+		if attachment.Network.Spec.Ingress {
+			continue
+		}
+		network := domain.NetworkAttachment{Name: attachment.Network.Spec.Name}
+		for _, address := range attachment.Addresses {
+			ip, _, err := net.ParseCIDR(address)
+			if err != nil {
+				continue
+			}
+			if ip.To4() != nil {
+				network.IPv4 = ip.String()
+			} else {
+				network.IPv6 = ip.String()
+			}
+		}
+		if network.IPv4 != "" || network.IPv6 != "" {
+			networks = append(networks, network)
+		}
+	}
+
+	container := domain.Container{ContainerMeta: meta, Networks: networks}
+	if len(networks) > 0 {
+		container.IP = networks[0].IPv4
+		if container.IP == "" {
+			container.IP = networks[0].IPv6
+		}
+	}
+	return container
+}
+
+// Changes просто периодически запрашивает перечитывание: задачи меняются
+// на всех узлах, а события Swarm приходят только на менеджеры.
+func (r *swarmRepository) Changes(ctx context.Context) <-chan struct{} {
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				select {
+				case out <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return out
+}
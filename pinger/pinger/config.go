@@ -0,0 +1,129 @@
+package pinger
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pinger/domain"
+	"pinger/probe"
+)
+
+// policy хранит действующую конфигурацию мониторинга от бэкенда.
+type policy struct {
+	mu     sync.RWMutex
+	config domain.MonitorConfig
+	loaded bool
+}
+
+// set заменяет конфигурацию и сообщает, изменилась ли она.
+func (p *policy) set(config domain.MonitorConfig) bool {
+	sort.SliceStable(config.Rules, func(i, j int) bool {
+		return config.Rules[i].Priority < config.Rules[j].Priority
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.loaded && p.config.Version == config.Version {
+		return false
+	}
+	p.config = config
+	p.loaded = true
+	return true
+}
+
+func (p *policy) rule(container domain.Container) (domain.MonitorRule, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.config.Rule(container.ContainerMeta)
+}
+
+// minInterval возвращает наименьший интервал из правил или 0.
+func (p *policy) minInterval() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var result time.Duration
+	for _, rule := range p.config.Rules {
+		interval := time.Duration(rule.Interval) * time.Second
+		if interval > 0 && (result == 0 || interval < result) {
+			result = interval
+		}
+	}
+	return result
+}
+
+// loadConfig перечитывает конфигурацию мониторинга и сообщает, изменилась ли
+// она. При ошибке остаётся прежняя конфигурация.
+func (s *PingerService) loadConfig(ctx context.Context) bool {
+	config, err := s.configRepo.GetConfig(ctx)
+	if err != nil {
+		log.Printf("Error loading monitor config, keeping previous one: %v", err)
+		return false
+	}
+	if !s.policy.set(config) {
+		return false
+	}
+	log.Printf("Applied monitor config version %d with %d rule(s)", config.Version, len(config.Rules))
+	return true
+}
+
+// excluded сообщает, исключена ли цель из мониторинга правилом.
+func (s *PingerService) excluded(container domain.Container) bool {
+	rule, ok := s.policy.rule(container)
+	return ok && rule.Exclude
+}
+
+// interval возвращает период проверок цели: из правила или общий.
+func (s *PingerService) interval(container domain.Container) time.Duration {
+	if rule, ok := s.policy.rule(container); ok && rule.Interval > 0 {
+		return time.Duration(rule.Interval) * time.Second
+	}
+	return s.cfg.Interval
+}
+
+// tick возвращает период обхода инвентаря: он не больше самого короткого
+// интервала проверок, иначе такие цели проверялись бы реже заданного.
+func (s *PingerService) tick() time.Duration {
+	tick := s.cfg.Interval
+	if interval := s.policy.minInterval(); interval > 0 && interval < tick {
+		tick = interval
+	}
+	return max(tick, time.Second)
+}
+
+// withRule возвращает копию цели, метки проверок которой дополнены настройками правила.
+func withRule(container domain.Container, rule domain.MonitorRule) domain.Container {
+	if len(rule.Probes) == 0 && len(rule.Options) == 0 {
+		return container
+	}
+	labels := make(map[string]string, len(container.Labels)+len(rule.Options)+1)
+	for k, v := range container.Labels {
+		labels[k] = v
+	}
+	for k, v := range rule.Options {
+		labels[k] = v
+	}
+	if len(rule.Probes) > 0 {
+		labels[probe.LabelProbes] = strings.Join(rule.Probes, ",")
+	}
+	container.Labels = labels
+	return container
+}
+
+// applyThreshold помечает недоступным ответ медленнее, чем разрешает правило.
+func applyThreshold(result *domain.PingResult, rule domain.MonitorRule) {
+	if rule.MaxLatencyMs <= 0 || !result.Status {
+		return
+	}
+	if result.PingTime*1000 > float64(rule.MaxLatencyMs) {
+		result.Status = false
+		result.Reason = domain.ReasonLatencyThreshold
+		result.LastSuccess = time.Time{}
+	}
+}
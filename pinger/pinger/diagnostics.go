@@ -0,0 +1,222 @@
+package pinger
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"pinger/domain"
+	"pinger/probe"
+)
+
+// diagnosticQueue — очередь запросов сетевой диагностики от бэкенда.
+const diagnosticQueue = "diagnostic_requests"
+
+// diagnosticTimeout ограничивает одну операцию над одним адресом или именем.
+const diagnosticTimeout = 30 * time.Second
+
+// maxHops — предел длины пути traceroute; цели обычно в паре хопов.
+const maxHops = 15
+
+// serveDiagnostics отвечает на запросы диагностики и переподключается при обрыве.
+func (s *PingerService) serveDiagnostics(ctx context.Context) {
+	for {
+		err := s.rabbitRepo.ServeRPC(ctx, diagnosticQueue, s.handleDiagnosticRequest)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Diagnostic request queue interrupted: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// handleDiagnosticRequest выполняет запрошенные операции над всеми адресами
+// контейнера параллельно и возвращает их результаты. На запрос о неизвестном
+// контейнере пингер сразу отвечает, что не знает его.
+func (s *PingerService) handleDiagnosticRequest(ctx context.Context, body []byte) []byte {
+	var request domain.DiagnosticRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return encodeDiagnostics(domain.DiagnosticResponse{Error: "invalid request: " + err.Error()})
+	}
+	response := domain.DiagnosticResponse{ContainerID: request.ContainerID}
+
+	container, ok := s.inventory.get(request.ContainerID)
+	if !ok || s.excluded(container) {
+		response.Error = domain.ProbeErrorNotFound
+		return encodeDiagnostics(response)
+	}
+	response.Identity = container.Identity
+
+	operations := request.Operations
+	if len(operations) == 0 {
+		operations = []string{domain.DiagnosticTraceroute, domain.DiagnosticMTU, domain.DiagnosticDNS}
+	}
+
+	var jobs []func(ctx context.Context) domain.DiagnosticResult
+	for _, operation := range operations {
+		switch operation {
+		case domain.DiagnosticTraceroute, domain.DiagnosticMTU:
+			for _, network := range s.probeTargets(container) {
+				ip := network.IPv4
+				if ip == "" {
+					ip = network.IPv6
+				}
+				jobs = append(jobs, addressDiagnostic(operation, network.Name, ip))
+			}
+		case domain.DiagnosticDNS:
+			jobs = append(jobs, s.dnsDiagnostics(container)...)
+		default:
+			response.Error = "unknown operation " + operation
+			return encodeDiagnostics(response)
+		}
+	}
+
+	log.Printf("Running %d diagnostic(s) for container %s", len(jobs), container.ID)
+	response.Results = make([]domain.DiagnosticResult, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jobCtx, cancel := context.WithTimeout(ctx, diagnosticTimeout)
+			defer cancel()
+			response.Results[i] = job(jobCtx)
+		}()
+	}
+	wg.Wait()
+	return encodeDiagnostics(response)
+}
+
+// addressDiagnostic возвращает traceroute или поиск MTU до адреса ip.
+func addressDiagnostic(operation, network, ip string) func(ctx context.Context) domain.DiagnosticResult {
+	return func(ctx context.Context) domain.DiagnosticResult {
+		result := domain.DiagnosticResult{
+			Operation: operation,
+			Target:    ip,
+			Network:   network,
+			Timestamp: time.Now(),
+		}
+		var err error
+		if operation == domain.DiagnosticTraceroute {
+			var trace domain.Traceroute
+			trace, err = probe.Traceroute(ctx, ip, maxHops)
+			result.Details = trace
+			result.Status = err == nil && trace.Reached
+		} else {
+			var mtu int
+			mtu, err = probe.PathMTU(ctx, ip)
+			result.Details = domain.PathMTU{MTU: mtu}
+			result.Status = err == nil
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	}
+}
+
+// dnsDiagnostic разрешает имя контейнера и сверяет ответ с его адресами.
+func dnsDiagnostic(name string, expected []string) func(ctx context.Context) domain.DiagnosticResult {
+	return func(ctx context.Context) domain.DiagnosticResult {
+		result := domain.DiagnosticResult{
+			Operation: domain.DiagnosticDNS,
+			Target:    name,
+			Timestamp: time.Now(),
+		}
+		addresses, err := probe.Lookup(ctx, probe.DockerDNS, name)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		for _, address := range addresses {
+			if contains(expected, address) {
+				result.Status = true
+			}
+		}
+		result.Details = domain.DNSLookup{
+			Server:    probe.DockerDNS,
+			Name:      name,
+			Addresses: addresses,
+			Expected:  expected,
+		}
+		return result
+	}
+}
+
+// dnsDiagnostics возвращает проверки имён контейнера во встроенном DNS
+// Docker. Пингер спрашивает свой 127.0.0.11, а тот знает только контейнеры
+// своего демона в сетях, к которым подключён пингер; для остальных целей
+// результат один — «неприменимо».
+func (s *PingerService) dnsDiagnostics(container domain.Container) []func(ctx context.Context) domain.DiagnosticResult {
+	// Без joiner пингер не знает, на каком хосте и в каких сетях он сам
+	if s.joiner == nil || s.joiner.repo == nil {
+		return []func(ctx context.Context) domain.DiagnosticResult{
+			notApplicable(domain.DiagnosticDNS, container.Name, "pinger does not run in a known container"),
+		}
+	}
+	if container.Source != domain.SourceDocker || container.Host != s.joiner.repo.Host() {
+		return []func(ctx context.Context) domain.DiagnosticResult{
+			notApplicable(domain.DiagnosticDNS, container.Name, "target is not on the pinger's Docker host"),
+		}
+	}
+
+	var names, addresses []string
+	add := func(name string) {
+		if name != "" && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, network := range container.Networks {
+		if !s.sharesNetwork(container, network.Name) {
+			continue
+		}
+		for _, alias := range network.Aliases {
+			add(alias)
+		}
+		if network.IPv4 != "" {
+			addresses = append(addresses, network.IPv4)
+		}
+		if network.IPv6 != "" {
+			addresses = append(addresses, network.IPv6)
+		}
+	}
+	if len(addresses) == 0 {
+		return []func(ctx context.Context) domain.DiagnosticResult{
+			notApplicable(domain.DiagnosticDNS, container.Name, "target shares no network with the pinger"),
+		}
+	}
+	add(container.Name)
+	add(container.ComposeService)
+
+	jobs := make([]func(ctx context.Context) domain.DiagnosticResult, 0, len(names))
+	for _, name := range names {
+		jobs = append(jobs, dnsDiagnostic(name, addresses))
+	}
+	return jobs
+}
+
+// notApplicable возвращает результат операции, которую нельзя выполнить для цели.
+func notApplicable(operation, target, reason string) func(ctx context.Context) domain.DiagnosticResult {
+	return func(context.Context) domain.DiagnosticResult {
+		return domain.DiagnosticResult{
+			Operation: operation,
+			Target:    target,
+			Error:     domain.DiagnosticNotApplicable + ": " + reason,
+			Timestamp: time.Now(),
+		}
+	}
+}
+
+func encodeDiagnostics(response domain.DiagnosticResponse) []byte {
+	body, err := json.Marshal(response)
+	if err != nil {
+		body, _ = json.Marshal(domain.DiagnosticResponse{ContainerID: response.ContainerID, Error: err.Error()})
+	}
+	return body
+}
@@ -0,0 +1,185 @@
+package pinger
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"pinger/probe"
+)
+
+// reference — опорная цель, по которой пингер судит о собственной сети.
+type reference struct {
+	name   string
+	target string
+}
+
+// recheckAge — насколько свежей должна быть проверка опорных целей, чтобы
+// сбой цели не требовал новой.
+const recheckAge = 5 * time.Second
+
+// environment хранит итог последней проверки опорных целей.
+type environment struct {
+	mu        sync.RWMutex
+	checked   bool
+	ok        bool
+	checkedAt time.Time
+	// checking не даёт проверять опорные цели одновременно
+	checking sync.Mutex
+}
+
+// healthy возвращает состояние сети пингера; nil, пока опорные цели не проверены.
+func (e *environment) healthy() *bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if !e.checked {
+		return nil
+	}
+	ok := e.ok
+	return &ok
+}
+
+// set сохраняет состояние и сообщает, изменилось ли оно.
+func (e *environment) set(ok bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	changed := !e.checked || e.ok != ok
+	e.checked, e.ok, e.checkedAt = true, ok, time.Now()
+	return changed
+}
+
+// age возвращает время с последней проверки опорных целей.
+func (e *environment) age() time.Duration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return time.Since(e.checkedAt)
+}
+
+// references возвращает опорные цели: шлюз по умолчанию, брокер и canary.
+// Шлюз ищется при каждой проверке, он меняется при подключении к сетям.
+func (s *PingerService) references() []reference {
+	var refs []reference
+	if s.cfg.ReferenceGateway {
+		if gateway := defaultGateway(); gateway != "" {
+			refs = append(refs, reference{name: "gateway", target: gateway})
+		}
+	}
+	if s.cfg.ReferenceBroker != "" {
+		refs = append(refs, reference{name: "broker", target: s.cfg.ReferenceBroker})
+	}
+	if s.cfg.Canary != "" {
+		refs = append(refs, reference{name: "canary", target: s.cfg.Canary})
+	}
+	return refs
+}
+
+// checkEnvironment проверяет опорные цели параллельно. Сеть пингера
+// считается неисправной, если не ответило больше половины целей: одна
+// молчащая цель (например, шлюз без ICMP) сама по себе ничего не значит.
+func (s *PingerService) checkEnvironment(ctx context.Context) {
+	s.env.checking.Lock()
+	defer s.env.checking.Unlock()
+
+	s.runEnvironmentCheck(ctx)
+}
+
+// recheckEnvironment повторяет проверку опорных целей, если последняя старше
+// recheckAge. Её вызывают при сбое цели: в начале отказа сети пингера
+// состояние с прошлого обхода ещё «исправно». Одновременные вызовы ждут одну
+// проверку.
+func (s *PingerService) recheckEnvironment(ctx context.Context) {
+	s.env.checking.Lock()
+	defer s.env.checking.Unlock()
+
+	if s.env.age() < recheckAge {
+		return
+	}
+	s.runEnvironmentCheck(ctx)
+}
+
+func (s *PingerService) runEnvironmentCheck(ctx context.Context) {
+	refs := s.references()
+	if len(refs) == 0 {
+		return
+	}
+
+	failed := make([]bool, len(refs))
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, s.cfg.ProbeTimeout)
+			defer cancel()
+			failed[i] = checkReference(ctx, ref.target) != nil
+		}()
+	}
+	wg.Wait()
+
+	var names []string
+	for i, ref := range refs {
+		if failed[i] {
+			names = append(names, ref.name+" "+ref.target)
+		}
+	}
+	ok := len(names)*2 <= len(refs)
+	if s.env.set(ok) {
+		if ok {
+			log.Printf("Probe environment healthy")
+		} else {
+			log.Printf("Probe environment impaired, unreachable references: %s", strings.Join(names, ", "))
+		}
+	}
+}
+
+// checkReference проверяет host:port подключением по TCP, а адрес без порта — ICMP.
+func checkReference(ctx context.Context, target string) error {
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	_, err := probe.Ping(ctx, target)
+	return err
+}
+
+// defaultGateway возвращает IPv4-шлюз маршрута по умолчанию из /proc/net/route.
+func defaultGateway() string {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags ...; адреса — hex в порядке байт хоста
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		if !ip.IsUnspecified() {
+			return ip.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package pinger
+
+import (
+	"sort"
+	"sync"
+
+	"pinger/domain"
+)
+
+// inventory хранит актуальный список контейнеров между обходами.
+type inventory struct {
+	mu         sync.RWMutex
+	containers map[string]domain.Container
+}
+
+func newInventory() *inventory {
+	return &inventory{containers: make(map[string]domain.Container)}
+}
+
+// replace заменяет цели одного источника на одном хосте, не трогая остальные,
+// и возвращает цели, которых раньше не было. Пустой host — источник охватывает
+// все хосты, и хост цели (например, узел задачи Swarm) сохраняется.
+func (i *inventory) replace(source, host string, containers []domain.Container) []domain.Container {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	previous := make(map[string]bool)
+	for id, c := range i.containers {
+		if c.Source == source && (host == "" || c.Host == host) {
+			previous[id] = true
+			delete(i.containers, id)
+		}
+	}
+
+	var added []domain.Container
+	for _, c := range containers {
+		c.Source = source
+		if host != "" {
+			c.Host = host
+		}
+		if !previous[c.ID] {
+			added = append(added, c)
+		}
+		i.containers[c.ID] = c
+	}
+	return added
+}
+
+func (i *inventory) upsert(c domain.Container) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.containers[c.ID] = c
+}
+
+func (i *inventory) remove(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.containers, id)
+}
+
+func (i *inventory) get(id string) (domain.Container, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	c, ok := i.containers[id]
+	return c, ok
+}
+
+func (i *inventory) snapshot() []domain.Container {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	result := make([]domain.Container, 0, len(i.containers))
+	for _, c := range i.containers {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].ID < result[b].ID })
+	return result
+}
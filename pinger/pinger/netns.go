@@ -0,0 +1,84 @@
+package pinger
+
+import (
+	"context"
+	"time"
+
+	"pinger/domain"
+	"pinger/probe"
+)
+
+// ProbeNetns проверяет контейнер изнутри его сетевого пространства имён:
+// доступность loopback, собственных адресов и TCP-портов на 127.0.0.1.
+// Это отделяет «сервис не слушает» от «сервис недоступен по сети».
+func (s *PingerService) ProbeNetns(ctx context.Context, container domain.Container) domain.PingResult {
+	result := domain.PingResult{
+		ContainerID: container.ID,
+		Host:        container.Host,
+		IP:          "127.0.0.1",
+		Mode:        domain.ModeNetns,
+		Timestamp:   time.Now(),
+		Container:   &container.ContainerMeta,
+	}
+	// Сеть и семейство адресов берутся из основного подключения контейнера,
+	// чтобы результат попадал в историю рядом с внешними проверками
+	if targets := s.probeTargets(container); len(targets) > 0 {
+		primary := targets[0]
+		ip := primary.IPv4
+		if ip == "" {
+			ip = primary.IPv6
+		}
+		result.Network = primary.Name
+		result.Family = probe.Family(ip)
+	}
+	details := &domain.NetnsResult{}
+	result.Netns = details
+
+	err := inNetns(container.PID, func() error {
+		loopback := s.ping(ctx, "127.0.0.1")
+		details.Loopback = loopback.Status
+		result.PingTime = loopback.PingTime
+
+		for _, network := range container.Networks {
+			for _, ip := range []string{network.IPv4, network.IPv6} {
+				if ip == "" || (ip == network.IPv6 && !s.cfg.IPv6) {
+					continue
+				}
+				details.Addresses = append(details.Addresses, domain.AddressCheck{
+					IP:     ip,
+					Status: s.ping(ctx, ip).Status,
+				})
+			}
+		}
+
+		for _, port := range container.Ports {
+			if port.Protocol != "tcp" {
+				continue
+			}
+			details.Ports = append(details.Ports, probe.DialTCP(ctx, "127.0.0.1", port.Port, s.cfg.ProbeTimeout))
+		}
+		return nil
+	})
+	if err != nil {
+		details.Error = err.Error()
+		return result
+	}
+
+	result.Status = details.Loopback
+	for _, port := range details.Ports {
+		result.Status = result.Status && port.Status
+	}
+	if result.Status {
+		result.LastSuccess = time.Now()
+	}
+	return result
+}
+
+// ping выполняет одну ICMP-проверку с таймаутом из конфигурации.
+func (s *PingerService) ping(ctx context.Context, ip string) domain.PingResult {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.ProbeTimeout)
+	defer cancel()
+
+	result, _ := s.PingContainer(ctx, ip)
+	return result
+}
@@ -0,0 +1,51 @@
+//go:build linux
+
+package pinger
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// inNetns выполняет fn в сетевом пространстве имён процесса pid.
+// fn запускается в отдельной горутине с закреплённым потоком ОС, поэтому
+// порождённые ею процессы (ping) наследуют это пространство имён.
+func inNetns(pid int, fn func() error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+
+		origin, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+		if err != nil {
+			runtime.UnlockOSThread()
+			errCh <- err
+			return
+		}
+		defer origin.Close()
+
+		target, err := os.Open(fmt.Sprintf("/proc/%d/ns/net", pid))
+		if err != nil {
+			runtime.UnlockOSThread()
+			errCh <- fmt.Errorf("open network namespace of pid %d: %w", pid, err)
+			return
+		}
+		defer target.Close()
+
+		if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			errCh <- fmt.Errorf("enter network namespace of pid %d: %w", pid, err)
+			return
+		}
+
+		errCh <- fn()
+
+		// Если вернуться не удалось, поток не отпускаем: Go завершит его вместе с горутиной
+		if err := unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET); err == nil {
+			runtime.UnlockOSThread()
+		}
+	}()
+	return <-errCh
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package pinger
+
+import "errors"
+
+func inNetns(pid int, fn func() error) error {
+	return errors.New("network namespaces are supported only on Linux")
+}
@@ -0,0 +1,160 @@
+package pinger
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"pinger/domain"
+	"pinger/internal/repository"
+)
+
+// networkJoiner подключает контейнер пингера к сетям целей, чтобы до них
+// можно было достучаться, и отключает, когда в сети не остаётся целей.
+type networkJoiner struct {
+	repo     repository.DockerRepository
+	self     string
+	autoJoin bool
+
+	mu sync.Mutex
+	// own — сети, к которым пингер подключён без нашего участия; их не трогаем
+	own map[string]bool
+	// joined — сети, подключённые пингером, и число целей в каждой
+	joined map[string]int
+}
+
+// newNetworkJoiner находит контейнер пингера среди демонов. Без него
+// (пингер запущен не в контейнере) сети не проверяются и не подключаются.
+func (s *PingerService) newNetworkJoiner(ctx context.Context) *networkJoiner {
+	self := s.cfg.SelfID
+	if self == "" {
+		// По умолчанию hostname контейнера — короткий ID
+		self, _ = os.Hostname()
+	}
+	if self == "" {
+		return nil
+	}
+
+	for _, repo := range s.dockerRepos {
+		container, err := repo.GetContainer(ctx, self)
+		if err != nil {
+			continue
+		}
+		j := &networkJoiner{
+			repo:     repo,
+			self:     container.ID,
+			autoJoin: s.cfg.AutoJoin,
+			own:      make(map[string]bool),
+			joined:   make(map[string]int),
+		}
+		for _, network := range container.Networks {
+			j.own[network.Name] = true
+		}
+		log.Printf("Pinger runs in container %s on host %s", container.ID, repo.Host())
+		return j
+	}
+	log.Printf("Pinger container %s not found, shared networks are not checked", self)
+	return nil
+}
+
+// sync пересчитывает число целей в каждой сети и подключает пингер к новым
+// сетям, а от ставших ненужными — отключает.
+func (j *networkJoiner) sync(ctx context.Context, containers []domain.Container) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	needed := make(map[string]int)
+	for _, c := range containers {
+		if c.ID == j.self {
+			j.refreshOwn(c)
+		}
+		// Сам пингер не в счёт, иначе подключённые сети никогда не освободятся
+		if c.Source != domain.SourceDocker || c.Host != j.repo.Host() || c.ID == j.self || c.State != domain.StateRunning {
+			continue
+		}
+		for _, network := range c.Networks {
+			if joinable(network.Name) {
+				needed[network.Name]++
+			}
+		}
+	}
+
+	if !j.autoJoin {
+		return
+	}
+
+	for network, count := range needed {
+		if j.own[network] {
+			continue
+		}
+		if _, ok := j.joined[network]; !ok {
+			if err := j.repo.ConnectNetwork(ctx, network, j.self); err != nil {
+				log.Printf("Failed to join network %s: %v", network, err)
+				continue
+			}
+			log.Printf("Joined network %s to reach %d target(s)", network, count)
+		}
+		j.joined[network] = count
+	}
+	for network := range j.joined {
+		if needed[network] > 0 {
+			continue
+		}
+		if err := j.repo.DisconnectNetwork(ctx, network, j.self); err != nil {
+			log.Printf("Failed to leave network %s: %v", network, err)
+			continue
+		}
+		delete(j.joined, network)
+		log.Printf("Left network %s: no targets remain", network)
+	}
+}
+
+// refreshOwn обновляет сети, к которым пингер подключён извне, по его
+// актуальным метаданным.
+func (j *networkJoiner) refreshOwn(self domain.Container) {
+	own := make(map[string]bool, len(self.Networks))
+	for _, network := range self.Networks {
+		if _, joined := j.joined[network.Name]; !joined {
+			own[network.Name] = true
+		}
+	}
+	j.own = own
+}
+
+// joinable отсекает сети, к которым нельзя подключить второй контейнер.
+func joinable(network string) bool {
+	return network != "host" && network != "none"
+}
+
+// shares сообщает, есть ли у пингера общая сеть с целью. Для целей на
+// других хостах и вне Docker это неизвестно, и считается, что есть.
+func (j *networkJoiner) shares(container domain.Container, network string) bool {
+	if container.Source != domain.SourceDocker || container.Host != j.repo.Host() || container.ID == j.self {
+		return true
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, joined := j.joined[network]
+	return j.own[network] || joined
+}
+
+// leave отключает пингер от всех сетей, к которым он подключался сам.
+func (j *networkJoiner) leave() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for network := range j.joined {
+		if err := j.repo.DisconnectNetwork(ctx, network, j.self); err != nil {
+			log.Printf("Failed to leave network %s: %v", network, err)
+			continue
+		}
+		delete(j.joined, network)
+	}
+}
@@ -0,0 +1,77 @@
+package pinger
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"pinger/domain"
+)
+
+// probeQueue — очередь запросов внеочередной проверки от бэкенда.
+const probeQueue = "probe_requests"
+
+// serveProbes отвечает на запросы проверки и переподключается при обрыве.
+func (s *PingerService) serveProbes(ctx context.Context) {
+	for {
+		err := s.rabbitRepo.ServeRPC(ctx, probeQueue, s.handleProbeRequest)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Probe request queue interrupted: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// handleProbeRequest проверяет контейнер сразу, не дожидаясь его слота в
+// расписании. Результаты публикуются как обычно и возвращаются в ответе.
+// На запрос о неизвестном контейнере пингер сразу отвечает, что не знает его.
+func (s *PingerService) handleProbeRequest(ctx context.Context, body []byte) []byte {
+	var response domain.ProbeResponse
+	var request domain.ProbeRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		response.Error = "invalid request: " + err.Error()
+		return encodeResponse(response)
+	}
+
+	container, ok := s.inventory.get(request.ContainerID)
+	if !ok {
+		response.Error = domain.ProbeErrorNotFound
+		return encodeResponse(response)
+	}
+	rule, _ := s.policy.rule(container)
+	if rule.Exclude {
+		response.Error = domain.ProbeErrorNotFound
+		return encodeResponse(response)
+	}
+
+	// Плановая проверка того же контейнера не должна идти параллельно
+	if !s.sched.wait(ctx, container) {
+		response.Error = ctx.Err().Error()
+		return encodeResponse(response)
+	}
+	defer s.sched.release(container)
+
+	log.Printf("On-demand probe of container %s", container.ID)
+	response.Results = s.check(ctx, container, rule)
+	for _, result := range response.Results {
+		if err := s.StorePingResult(ctx, result); err != nil {
+			log.Printf("Error storing ping result for container %s: %v", container.ID, err)
+		}
+	}
+	return encodeResponse(response)
+}
+
+func encodeResponse(response domain.ProbeResponse) []byte {
+	body, err := json.Marshal(response)
+	if err != nil {
+		body, _ = json.Marshal(domain.ProbeResponse{Error: err.Error()})
+	}
+	return body
+}
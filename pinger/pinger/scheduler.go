@@ -0,0 +1,228 @@
+package pinger
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"pinger/domain"
+)
+
+// Config задаёт параметры планирования проверок.
+type Config struct {
+	// Interval — период между проверками одного контейнера.
+	Interval time.Duration
+	// Workers — максимальное число одновременных проверок.
+	Workers int
+	// ProbeTimeout ограничивает длительность одной проверки (одного адреса или порта).
+	ProbeTimeout time.Duration
+	// Jitter — доля интервала (0..1), на которую размазывается старт проверок.
+	Jitter float64
+	// ResyncInterval — период полной сверки инвентаря с Docker.
+	ResyncInterval time.Duration
+	// Networks ограничивает проверки указанными сетями; пустой список — все сети.
+	Networks []string
+	// IPv6 включает проверку IPv6-адресов.
+	IPv6 bool
+	// Netns включает проверки изнутри сетевого пространства имён контейнера.
+	Netns bool
+	// StatsInterval — период сбора потребления ресурсов; 0 отключает сбор.
+	StatsInterval time.Duration
+	// SelfID — ID контейнера пингера; по умолчанию берётся hostname.
+	SelfID string
+	// AutoJoin подключает пингер к сетям целей, с которыми у него нет общей сети.
+	AutoJoin bool
+	// ReferenceGateway включает шлюз по умолчанию в опорные цели.
+	ReferenceGateway bool
+	// ReferenceBroker — адрес брокера host:port как опорная цель.
+	ReferenceBroker string
+	// Canary — дополнительная опорная цель: host:port проверяется по TCP, адрес — ICMP.
+	Canary string
+}
+
+// scheduler раздаёт проверки пулу воркеров и не допускает наложения
+// проверок одного и того же контейнера.
+type scheduler struct {
+	cfg   Config
+	jobs  chan domain.Container
+	probe func(ctx context.Context, container domain.Container)
+	// interval возвращает период проверок контейнера
+	interval func(container domain.Container) time.Duration
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+	// last — время последней постановки контейнера в очередь
+	last map[string]time.Time
+}
+
+// withDefaults подставляет значения по умолчанию для незаданных параметров.
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = 1
+	}
+	if c.ProbeTimeout <= 0 {
+		c.ProbeTimeout = time.Second
+	}
+	if c.ResyncInterval <= 0 {
+		c.ResyncInterval = 5 * time.Minute
+	}
+	if c.Jitter < 0 {
+		c.Jitter = 0
+	}
+	if c.Jitter > 1 {
+		c.Jitter = 1
+	}
+	return c
+}
+
+func newScheduler(cfg Config, probe func(ctx context.Context, container domain.Container), interval func(container domain.Container) time.Duration) *scheduler {
+	return &scheduler{
+		cfg:      cfg,
+		jobs:     make(chan domain.Container),
+		probe:    probe,
+		interval: interval,
+		inFlight: make(map[string]bool),
+		last:     make(map[string]time.Time),
+	}
+}
+
+func (s *scheduler) start(ctx context.Context) {
+	for i := 0; i < s.cfg.Workers; i++ {
+		go s.worker(ctx)
+	}
+}
+
+func (s *scheduler) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case container := <-s.jobs:
+			s.probe(ctx, container)
+			s.release(container)
+		}
+	}
+}
+
+// schedule ставит в очередь контейнеры, чей интервал истекает к этому обходу;
+// обходы идут раз в tick. Контейнеры, чья предыдущая проверка ещё не
+// завершилась, пропускаются.
+func (s *scheduler) schedule(ctx context.Context, containers []domain.Container, tick time.Duration) {
+	now := time.Now()
+	present := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		present[targetKey(container)] = true
+		interval := s.interval(container)
+		if !s.due(container, now, interval-tick/2) {
+			continue
+		}
+		s.dispatch(ctx, container, s.offset(container, min(interval, tick)))
+	}
+	s.forget(present)
+}
+
+// scheduleNow проверяет контейнеры без ожидания своего слота в интервале.
+func (s *scheduler) scheduleNow(ctx context.Context, containers []domain.Container) {
+	for _, container := range containers {
+		s.dispatch(ctx, container, 0)
+	}
+}
+
+func (s *scheduler) dispatch(ctx context.Context, container domain.Container, delay time.Duration) {
+	if !s.acquire(container) {
+		log.Printf("Skipping container %s: previous probe is still running", targetKey(container))
+		return
+	}
+
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			s.release(container)
+			return
+		case <-timer.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			s.release(container)
+		case s.jobs <- container:
+		}
+	}()
+}
+
+// due сообщает, прошло ли с последней постановки контейнера не меньше interval.
+func (s *scheduler) due(container domain.Container, now time.Time, interval time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return now.Sub(s.last[targetKey(container)]) >= interval
+}
+
+// forget удаляет время постановки контейнеров, пропавших из инвентаря.
+func (s *scheduler) forget(present map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.last {
+		if !present[key] {
+			delete(s.last, key)
+		}
+	}
+}
+
+// offset возвращает стабильный для контейнера сдвиг старта внутри периода,
+// чтобы проверки не стартовали одновременно.
+func (s *scheduler) offset(container domain.Container, period time.Duration) time.Duration {
+	window := time.Duration(float64(period) * s.cfg.Jitter)
+	if window <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(targetKey(container)))
+	return time.Duration(h.Sum64() % uint64(window))
+}
+
+func (s *scheduler) acquire(container domain.Container) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := targetKey(container)
+	if s.inFlight[key] {
+		return false
+	}
+	s.inFlight[key] = true
+	s.last[key] = time.Now()
+	return true
+}
+
+// wait занимает контейнер, дождавшись окончания его текущей проверки.
+// Возвращает false, если ctx истёк раньше.
+func (s *scheduler) wait(ctx context.Context, container domain.Container) bool {
+	for !s.acquire(container) {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return true
+}
+
+func (s *scheduler) release(container domain.Container) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, targetKey(container))
+}
+
+func targetKey(container domain.Container) string {
+	if container.ID != "" {
+		return container.ID
+	}
+	return container.IP
+}
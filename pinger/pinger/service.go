@@ -2,38 +2,95 @@ package pinger
 
 import (
 	"context"
+	"encoding/json"
 	"log"
-	"os/exec"
+	"math"
 	"pinger/domain"
 	"pinger/internal/repository"
+	"pinger/probe"
+	"sync"
 	"time"
 )
 
 type PingerService struct {
-	dockerRepo repository.DockerRepository
-	rabbitRepo repository.RabbitMQRepository
+	// dockerRepos — по одному репозиторию на каждый демон Docker
+	dockerRepos []repository.DockerRepository
+	rabbitRepo  repository.RabbitMQRepository
+	outboxRepo  repository.OutboxRepository
+	// configRepo — конфигурация мониторинга от бэкенда; nil, если бэкенд не задан
+	configRepo repository.ConfigRepository
+	policy     *policy
+	cfg        Config
+	inventory  *inventory
+	registry   *probe.Registry
+	// sources — дополнительные источники целей помимо Docker
+	sources []repository.TargetRepository
+	tasks   *taskStatus
+	// env — состояние собственной сети пингера по опорным целям
+	env *environment
+	// joiner следит за общими сетями с целями; nil, если пингер не в контейнере
+	joiner *networkJoiner
+	// sched — планировщик проверок; создаётся в Run
+	sched *scheduler
+	// publishMu упорядочивает отправку: сообщение не обгонит отложенные в outbox
+	publishMu sync.Mutex
 }
 
-func NewPingerService(dockerRepo repository.DockerRepository, rabbitRepo repository.RabbitMQRepository) *PingerService {
+func NewPingerService(dockerRepos []repository.DockerRepository, rabbitRepo repository.RabbitMQRepository, outboxRepo repository.OutboxRepository, configRepo repository.ConfigRepository, cfg Config, registry *probe.Registry, sources ...repository.TargetRepository) *PingerService {
 	return &PingerService{
-		dockerRepo: dockerRepo,
-		rabbitRepo: rabbitRepo,
+		dockerRepos: dockerRepos,
+		rabbitRepo:  rabbitRepo,
+		outboxRepo:  outboxRepo,
+		configRepo:  configRepo,
+		policy:      &policy{},
+		cfg:         cfg.withDefaults(),
+		inventory:   newInventory(),
+		registry:    registry,
+		sources:     sources,
+		tasks:       newTaskStatus(),
+		env:         &environment{},
 	}
 }
 
+// GetContainers возвращает контейнеры всех демонов; недоступные демоны пропускаются.
 func (s *PingerService) GetContainers(ctx context.Context) ([]domain.Container, error) {
-	return s.dockerRepo.GetContainers()
+	var result []domain.Container
+	var lastErr error
+	for _, repo := range s.dockerRepos {
+		containers, err := repo.GetContainers(ctx)
+		if err != nil {
+			log.Printf("Error fetching containers from host %s: %v", repo.Host(), err)
+			lastErr = err
+			continue
+		}
+		result = append(result, containers...)
+	}
+	if len(result) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return result, nil
+}
+
+// dockerRepo возвращает репозиторий демона host.
+func (s *PingerService) dockerRepo(host string) repository.DockerRepository {
+	for _, repo := range s.dockerRepos {
+		if repo.Host() == host {
+			return repo
+		}
+	}
+	return nil
 }
 
 func (s *PingerService) PingContainer(ctx context.Context, ip string) (domain.PingResult, error) {
 	start := time.Now()
-	cmd := exec.CommandContext(ctx, "ping", "-c", "1", "-W", "1", ip)
-	err := cmd.Run()
-	pingTime := time.Since(start).Seconds()
+	pingTime, err := probe.Ping(ctx, ip)
 
 	result := domain.PingResult{
-		IP:       ip,
-		PingTime: pingTime,
+		IP:        ip,
+		Family:    probe.Family(ip),
+		Timestamp: start,
+		PingTime:  pingTime,
+		Status:    err == nil,
 	}
 
 	if err == nil {
@@ -43,42 +100,419 @@ func (s *PingerService) PingContainer(ctx context.Context, ip string) (domain.Pi
 	return result, nil
 }
 
+// probeTargets возвращает пары сеть/адрес, которые нужно проверить у контейнера.
+func (s *PingerService) probeTargets(container domain.Container) []domain.NetworkAttachment {
+	var targets []domain.NetworkAttachment
+	for _, network := range container.Networks {
+		if len(s.cfg.Networks) > 0 && !contains(s.cfg.Networks, network.Name) {
+			continue
+		}
+		if network.IPv4 != "" {
+			targets = append(targets, domain.NetworkAttachment{Name: network.Name, IPv4: network.IPv4})
+		}
+		if s.cfg.IPv6 && network.IPv6 != "" {
+			targets = append(targets, domain.NetworkAttachment{Name: network.Name, IPv6: network.IPv6})
+		}
+	}
+	return targets
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *PingerService) StorePingResult(ctx context.Context, result domain.PingResult) error {
-	return s.rabbitRepo.PublishPingResult(result)
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.publish("ping_results", body)
+}
+
+// publish отправляет сообщение в RabbitMQ, а при недоступности брокера
+// откладывает его в outbox. Пока outbox не пуст, новые сообщения тоже
+// ставятся в очередь, чтобы сохранить порядок.
+func (s *PingerService) publish(routingKey string, body []byte) error {
+	msg := domain.OutboxMessage{RoutingKey: routingKey, Body: body}
+
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+
+	if s.outboxRepo.Pending() {
+		if err := s.outboxRepo.Append(msg); err != nil {
+			return err
+		}
+		s.flushOutbox()
+		return nil
+	}
+
+	if err := s.rabbitRepo.Publish(routingKey, body); err != nil {
+		log.Printf("Broker unavailable, spooling message to outbox: %v", err)
+		return s.outboxRepo.Append(msg)
+	}
+	return nil
+}
+
+// FlushOutbox повторно отправляет отложенные сообщения в исходном порядке.
+func (s *PingerService) FlushOutbox() {
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	s.flushOutbox()
+}
+
+func (s *PingerService) flushOutbox() {
+	if !s.outboxRepo.Pending() {
+		return
+	}
+	err := s.outboxRepo.Drain(func(msg domain.OutboxMessage) error {
+		return s.rabbitRepo.Publish(msg.RoutingKey, msg.Body)
+	})
+	if err != nil {
+		log.Printf("Outbox replay interrupted: %v", err)
+		return
+	}
+	log.Println("Outbox replayed successfully")
 }
 
-func (s *PingerService) Run(ctx context.Context, interval time.Duration) {
-	ticker := time.NewTicker(interval)
+func (s *PingerService) Run(ctx context.Context) {
+	s.joiner = s.newNetworkJoiner(ctx)
+	if s.joiner != nil && s.cfg.AutoJoin {
+		defer s.joiner.leave()
+	}
+
+	// Конфигурация нужна до первых проверок, чтобы не проверять исключённые цели
+	var configChanges <-chan struct{}
+	if s.configRepo != nil {
+		s.loadConfig(ctx)
+		configChanges = s.configRepo.Changes(ctx)
+	}
+
+	sched := newScheduler(s.cfg, s.probe, s.interval)
+	s.sched = sched
+	sched.start(ctx)
+	s.checkEnvironment(ctx)
+
+	// Первичное заполнение инвентаря, дальше он поддерживается событиями Docker.
+	// Каждый демон обнаруживается независимо, недоступность одного не мешает остальным.
+	for _, repo := range s.dockerRepos {
+		s.resync(ctx, sched, repo)
+		go s.watchEvents(ctx, sched, repo)
+	}
+	for _, source := range s.sources {
+		s.loadSource(ctx, sched, source)
+		go s.watchSource(ctx, sched, source)
+	}
+	if s.cfg.StatsInterval > 0 {
+		go s.collectStats(ctx)
+	}
+	go s.serveProbes(ctx)
+	go s.serveDiagnostics(ctx)
+
+	tick := s.tick()
+	ticker := time.NewTicker(tick)
 	defer ticker.Stop()
+	resyncTicker := time.NewTicker(s.cfg.ResyncInterval)
+	defer resyncTicker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Stopping pinger service...")
 			return
-		case <-ticker.C:
-			containers, err := s.GetContainers(ctx)
-			if err != nil {
-				log.Printf("Error fetching containers: %v", err)
-				continue
+		case <-resyncTicker.C:
+			for _, repo := range s.dockerRepos {
+				s.resync(ctx, sched, repo)
 			}
+		case <-configChanges:
+			if s.loadConfig(ctx) {
+				tick = s.tick()
+				ticker.Reset(tick)
+			}
+		case <-ticker.C:
+			s.FlushOutbox()
+			s.checkEnvironment(ctx)
+			s.publishServiceHealth(ctx)
+			sched.schedule(ctx, s.inventory.snapshot(), tick)
+		}
+	}
+}
 
-			for _, container := range containers {
-				result, err := s.PingContainer(ctx, container.IP)
-				if err != nil {
-					log.Printf("Error pinging container %s: %v", container.IP, err)
-					continue
-				}
+// resync перечитывает полный список контейнеров демона на случай пропущенных событий.
+func (s *PingerService) resync(ctx context.Context, sched *scheduler, repo repository.DockerRepository) {
+	containers, err := repo.GetContainers(ctx)
+	if err != nil {
+		log.Printf("Error fetching containers from host %s: %v", repo.Host(), err)
+		return
+	}
+
+	added := s.inventory.replace(domain.SourceDocker, repo.Host(), containers)
+	s.syncNetworks(ctx)
+	if len(added) > 0 {
+		log.Printf("Resync found %d new container(s) on host %s", len(added), repo.Host())
+		sched.scheduleNow(ctx, added)
+	}
+}
+
+// loadSource перечитывает цели источника. При ошибке остаются прежние цели,
+// чтобы опечатка в конфигурации не снимала их с мониторинга.
+func (s *PingerService) loadSource(ctx context.Context, sched *scheduler, source repository.TargetRepository) {
+	targets, err := source.GetTargets(ctx)
+	if err != nil {
+		log.Printf("Error loading targets from %s source, keeping previous ones: %v", source.Source(), err)
+		return
+	}
+
+	added := s.inventory.replace(source.Source(), "", targets)
+	log.Printf("Loaded %d target(s) from %s source", len(targets), source.Source())
+	if len(added) > 0 {
+		sched.scheduleNow(ctx, added)
+	}
+}
+
+func (s *PingerService) watchSource(ctx context.Context, sched *scheduler, source repository.TargetRepository) {
+	for range source.Changes(ctx) {
+		s.loadSource(ctx, sched, source)
+	}
+}
+
+// watchEvents держит подписку на события Docker и переподключается при обрыве.
+func (s *PingerService) watchEvents(ctx context.Context, sched *scheduler, repo repository.DockerRepository) {
+	for {
+		events, errs := repo.Events(ctx)
+		log.Printf("Subscribed to Docker events on host %s", repo.Host())
 
-				err = s.StorePingResult(ctx, result)
-				if err != nil {
-					log.Printf("Error storing ping result for container %s: %v", container.IP, err)
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-errs:
+				log.Printf("Docker events stream of host %s interrupted: %v", repo.Host(), err)
+				break loop
+			case event, ok := <-events:
+				if !ok {
+					break loop
 				}
+				s.handleEvent(ctx, sched, repo, event)
 			}
 		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+		// За время обрыва могли быть пропущены события
+		s.resync(ctx, sched, repo)
+	}
+}
+
+func (s *PingerService) handleEvent(ctx context.Context, sched *scheduler, repo repository.DockerRepository, event domain.ContainerEvent) {
+	if event.ContainerID == "" {
+		return
+	}
+	log.Printf("Docker event %s for container %s on host %s", event.Action, event.ContainerID, repo.Host())
+
+	switch event.Action {
+	case "destroy":
+		s.inventory.remove(event.ContainerID)
+		s.syncNetworks(ctx)
+	default:
+		// Остановленные контейнеры остаются в инвентаре, чтобы бэкенд видел их состояние
+		container, err := repo.GetContainer(ctx, event.ContainerID)
+		if repository.IsNotFound(err) {
+			s.inventory.remove(event.ContainerID)
+			s.syncNetworks(ctx)
+			break
+		}
+		if err != nil {
+			log.Printf("Error inspecting container %s: %v", event.ContainerID, err)
+			break
+		}
+		s.inventory.upsert(container)
+		s.syncNetworks(ctx)
+		sched.scheduleNow(ctx, []domain.Container{container})
+	}
+
+	if err := s.PublishEvent(ctx, event); err != nil {
+		log.Printf("Error publishing event for container %s: %v", event.ContainerID, err)
+	}
+}
+
+// PublishEvent отправляет событие жизненного цикла контейнера в бэкенд.
+func (s *PingerService) PublishEvent(ctx context.Context, event domain.ContainerEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.publish("container_events", body)
+}
+
+func (s *PingerService) probe(ctx context.Context, container domain.Container) {
+	rule, _ := s.policy.rule(container)
+	for _, result := range s.check(ctx, container, rule) {
+		if err := s.StorePingResult(ctx, result); err != nil {
+			log.Printf("Error storing ping result for container %s: %v", container.ID, err)
+		}
+	}
+}
+
+// check проверяет контейнер по всем адресам и возвращает результаты с
+// состоянием сети пингера и метаданными без секретных меток, не публикуя их.
+// Исключённая правилом цель не проверяется, но о её состоянии бэкенд узнаёт
+// как обычно, иначе сочтёт её пропавшей.
+func (s *PingerService) check(ctx context.Context, container domain.Container, rule domain.MonitorRule) []domain.PingResult {
+	var results []domain.PingResult
+	if rule.Exclude {
+		results = []domain.PingResult{stateResult(container)}
+	} else {
+		results = s.checkTargets(ctx, container, rule)
+	}
+	healthy := s.env.healthy()
+	if healthy != nil && *healthy && failed(results) {
+		s.recheckEnvironment(ctx)
+		healthy = s.env.healthy()
+	}
+	// Метки с учётными данными нужны проверкам, но наружу не уходят
+	meta := container.ContainerMeta
+	meta.Labels = domain.PublicLabels(meta.Labels)
+	for i := range results {
+		results[i].EnvironmentHealthy = healthy
+		results[i].Container = &meta
+	}
+	return results
+}
+
+// checkTargets выполняет проверки контейнера. Для остановленного контейнера
+// результат один — с его состоянием.
+func (s *PingerService) checkTargets(ctx context.Context, container domain.Container, rule domain.MonitorRule) []domain.PingResult {
+	targets := s.probeTargets(container)
+	if container.State != domain.StateRunning || len(targets) == 0 {
+		// Проверять нечего, но бэкенд должен узнать о состоянии контейнера
+		if container.Swarm != nil {
+			s.tasks.set(container.ID, false)
+		}
+		return []domain.PingResult{stateResult(container)}
+	}
+
+	var results []domain.PingResult
+	reachable := true
+
+	for _, network := range targets {
+		ip := network.IPv4
+		if ip == "" {
+			ip = network.IPv6
+		}
+
+		if !s.sharesNetwork(container, network.Name) {
+			// Проверка всё равно не пройдёт, а настоящий сбой маскировать нельзя
+			results = append(results, domain.PingResult{
+				ContainerID: container.ID,
+				Host:        container.Host,
+				IP:          ip,
+				Network:     network.Name,
+				Family:      probe.Family(ip),
+				Mode:        domain.ModeExternal,
+				Timestamp:   time.Now(),
+				Reason:      domain.ReasonNoSharedNetwork,
+				Container:   &container.ContainerMeta,
+			})
+			reachable = false
+			continue
+		}
+
+		start := time.Now()
+		target := probe.Target{Container: withRule(container, rule), Network: network.Name, IP: ip}
+		result := domain.PingResult{
+			ContainerID: container.ID,
+			Host:        container.Host,
+			IP:          ip,
+			Network:     network.Name,
+			Family:      probe.Family(ip),
+			Mode:        domain.ModeExternal,
+			Timestamp:   start,
+			Probes:      s.registry.Run(ctx, target),
+			Container:   &container.ContainerMeta,
+		}
+		summarize(&result)
+		applyThreshold(&result, rule)
+		reachable = reachable && result.Status
+		results = append(results, result)
+	}
+
+	if container.Swarm != nil {
+		s.tasks.set(container.ID, reachable)
+	}
+
+	if s.cfg.Netns && container.PID > 0 {
+		result := s.ProbeNetns(ctx, container)
+		if result.Netns.Error != "" {
+			log.Printf("Netns probe of container %s failed: %s", container.ID, result.Netns.Error)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// failed сообщает, есть ли среди результатов сбой самой проверки.
+func failed(results []domain.PingResult) bool {
+	for _, result := range results {
+		if len(result.Probes) > 0 && !result.Status && result.Reason == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// stateResult возвращает результат без проверок, только с состоянием цели.
+func stateResult(container domain.Container) domain.PingResult {
+	return domain.PingResult{
+		ContainerID: container.ID,
+		Host:        container.Host,
+		Timestamp:   time.Now(),
+		Container:   &container.ContainerMeta,
+	}
+}
+
+// syncNetworks подключает пингер к сетям целей, если включён AutoJoin.
+func (s *PingerService) syncNetworks(ctx context.Context) {
+	if s.joiner != nil {
+		s.joiner.sync(ctx, s.inventory.snapshot())
+	}
+}
+
+// sharesNetwork сообщает, может ли пингер достучаться до цели в сети network.
+func (s *PingerService) sharesNetwork(container domain.Container, network string) bool {
+	return s.joiner == nil || s.joiner.shares(container, network)
+}
+
+// summarize заполняет итоговые поля результата: контейнер доступен, только
+// если прошли все проверки. Время берётся из ICMP-проверки, а без неё — как
+// наибольшая задержка среди всех проверок.
+func summarize(result *domain.PingResult) {
+	result.Status = len(result.Probes) > 0
+	result.PingTime = 0
+	icmp := false
+	for _, p := range result.Probes {
+		result.Status = result.Status && p.Status
+		switch {
+		case p.Type == "icmp" && !icmp:
+			icmp = true
+			result.PingTime = p.Latency
+		case !icmp:
+			result.PingTime = math.Max(result.PingTime, p.Latency)
+		}
+	}
+	if result.Status {
+		result.LastSuccess = time.Now()
 	}
 }
 
-func (s *PingerService) Start(ctx context.Context, interval time.Duration) {
-	go s.Run(ctx, interval)
+func (s *PingerService) Start(ctx context.Context) {
+	go s.Run(ctx)
 }
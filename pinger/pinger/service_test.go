@@ -0,0 +1,73 @@
+package pinger
+
+import (
+	"testing"
+
+	"pinger/domain"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name       string
+		probes     []domain.ProbeResult
+		wantStatus bool
+		wantTime   float64
+	}{
+		{
+			name:       "no probes",
+			wantStatus: false,
+		},
+		{
+			name: "all probes pass",
+			probes: []domain.ProbeResult{
+				{Type: "icmp", Status: true, Latency: 0.001},
+				{Type: "http", Status: true, Latency: 0.02},
+			},
+			wantStatus: true,
+			wantTime:   0.001,
+		},
+		{
+			name: "icmp passes but http fails",
+			probes: []domain.ProbeResult{
+				{Type: "icmp", Status: true, Latency: 0.001},
+				{Type: "http", Status: false, Latency: 0.5},
+			},
+			wantStatus: false,
+			wantTime:   0.001,
+		},
+		{
+			name: "http fails before icmp",
+			probes: []domain.ProbeResult{
+				{Type: "http", Status: false, Latency: 0.5},
+				{Type: "icmp", Status: true, Latency: 0.001},
+			},
+			wantStatus: false,
+			wantTime:   0.001,
+		},
+		{
+			name: "without icmp the slowest probe wins",
+			probes: []domain.ProbeResult{
+				{Type: "tcp", Status: true, Latency: 0.003},
+				{Type: "http", Status: true, Latency: 0.02},
+			},
+			wantStatus: true,
+			wantTime:   0.02,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := domain.PingResult{Probes: tt.probes}
+			summarize(&result)
+			if result.Status != tt.wantStatus {
+				t.Errorf("Status = %v, want %v", result.Status, tt.wantStatus)
+			}
+			if result.PingTime != tt.wantTime {
+				t.Errorf("PingTime = %v, want %v", result.PingTime, tt.wantTime)
+			}
+			if result.Status == result.LastSuccess.IsZero() {
+				t.Errorf("LastSuccess = %v with Status %v", result.LastSuccess, result.Status)
+			}
+		})
+	}
+}
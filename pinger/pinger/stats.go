@@ -0,0 +1,73 @@
+package pinger
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"pinger/domain"
+)
+
+// collectStats периодически снимает потребление ресурсов запущенными
+// контейнерами. Следующий обход не начинается, пока не закончен предыдущий.
+func (s *PingerService) collectStats(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.StatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sampleStats(ctx)
+		}
+	}
+}
+
+func (s *PingerService) sampleStats(ctx context.Context) {
+	sem := make(chan struct{}, s.cfg.Workers)
+	var wg sync.WaitGroup
+
+	for _, container := range s.inventory.snapshot() {
+		if container.Source != domain.SourceDocker || container.State != domain.StateRunning || s.excluded(container) {
+			continue
+		}
+		repo := s.dockerRepo(container.Host)
+		if repo == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			stats, err := repo.Stats(ctx, id)
+			if err != nil {
+				log.Printf("Error collecting stats of container %s on host %s: %v", id, repo.Host(), err)
+				return
+			}
+			if err := s.PublishStats(ctx, stats); err != nil {
+				log.Printf("Error publishing stats of container %s: %v", id, err)
+			}
+		}(container.ID)
+	}
+	wg.Wait()
+}
+
+// PublishStats отправляет выборку потребления ресурсов в бэкенд.
+func (s *PingerService) PublishStats(ctx context.Context, stats domain.ContainerStats) error {
+	body, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return s.publish("container_stats", body)
+}
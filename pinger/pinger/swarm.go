@@ -0,0 +1,97 @@
+package pinger
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"pinger/domain"
+)
+
+// taskStatus хранит итог последней проверки каждой задачи Swarm.
+type taskStatus struct {
+	mu     sync.Mutex
+	status map[string]bool
+}
+
+func newTaskStatus() *taskStatus {
+	return &taskStatus{status: make(map[string]bool)}
+}
+
+func (t *taskStatus) set(id string, reachable bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status[id] = reachable
+}
+
+// serviceHealth сводит последние результаты задач по сервисам и забывает
+// задачи, которых больше нет в инвентаре.
+func (t *taskStatus) serviceHealth(containers []domain.Container) []domain.ServiceHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	services := make(map[string]*domain.ServiceHealth)
+	current := make(map[string]bool)
+	for _, c := range containers {
+		if c.Swarm == nil {
+			continue
+		}
+		current[c.ID] = true
+
+		health, ok := services[c.Swarm.ServiceID]
+		if !ok {
+			health = &domain.ServiceHealth{
+				ServiceID:   c.Swarm.ServiceID,
+				ServiceName: c.Swarm.ServiceName,
+				Desired:     c.Swarm.DesiredTasks,
+				Timestamp:   now,
+			}
+			services[c.Swarm.ServiceID] = health
+		}
+		if c.State == domain.StateRunning {
+			health.Running++
+		}
+		if t.status[c.ID] {
+			health.Reachable++
+		}
+	}
+	for id := range t.status {
+		if !current[id] {
+			delete(t.status, id)
+		}
+	}
+
+	result := make([]domain.ServiceHealth, 0, len(services))
+	for _, health := range services {
+		health.Status = health.Reachable >= health.Desired
+		result = append(result, *health)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ServiceName < result[j].ServiceName })
+	return result
+}
+
+// publishServiceHealth отправляет в бэкенд сводку N из M по сервисам Swarm.
+// Задачи, исключённые правилами мониторинга, не проверяются и в сводку не входят.
+func (s *PingerService) publishServiceHealth(ctx context.Context) {
+	var monitored []domain.Container
+	for _, c := range s.inventory.snapshot() {
+		if !s.excluded(c) {
+			monitored = append(monitored, c)
+		}
+	}
+	for _, health := range s.tasks.serviceHealth(monitored) {
+		body, err := json.Marshal(health)
+		if err != nil {
+			log.Printf("Error encoding health of service %s: %v", health.ServiceName, err)
+			continue
+		}
+		if err := s.publish("service_health", body); err != nil {
+			log.Printf("Error publishing health of service %s: %v", health.ServiceName, err)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/streadway/amqp"
+
+	"pinger/domain"
+)
+
+// Виды хранилищ, для которых выполняется проверка на уровне протокола.
+// Проверка включается меткой monitor.<kind>=true или monitor.<kind>.port.
+const (
+	DatastorePostgres = "postgres"
+	DatastoreRedis    = "redis"
+	DatastoreAMQP     = "amqp"
+)
+
+var datastoreDefaultPorts = map[string]string{
+	DatastorePostgres: "5432",
+	DatastoreRedis:    "6379",
+	DatastoreAMQP:     "5672",
+}
+
+// Credentials — учётные данные хранилища из файла секретов. В метках их не
+// держат: метки контейнера публикуются вместе с результатами.
+type Credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// Database — имя базы PostgreSQL или vhost RabbitMQ.
+	Database string `json:"database"`
+}
+
+// LoadSecrets читает JSON-файл вида {"<имя>": {"user": ..., "password": ...}}.
+// На секрет ссылаются меткой monitor.<kind>.secret=<имя>.
+func LoadSecrets(path string) (map[string]Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var secrets map[string]Credentials
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("parse secrets file %s: %w", path, err)
+	}
+	return secrets, nil
+}
+
+type datastoreProber struct {
+	kind    string
+	timeout time.Duration
+	secrets map[string]Credentials
+}
+
+// NewDatastore создаёт протокольную проверку хранилища kind (postgres, redis или amqp).
+func NewDatastore(kind string, timeout time.Duration, secrets map[string]Credentials) Prober {
+	return &datastoreProber{kind: kind, timeout: timeout, secrets: secrets}
+}
+
+func (p *datastoreProber) Name() string { return p.kind }
+
+func (p *datastoreProber) Schema() []Option {
+	prefix := "monitor." + p.kind
+	database := "database name"
+	if p.kind == DatastoreAMQP {
+		database = "virtual host"
+	}
+	return []Option{
+		{Label: prefix, Description: "set to true to enable the probe"},
+		{Label: prefix + ".port", Description: "port, enables the probe", Default: datastoreDefaultPorts[p.kind]},
+		{Label: prefix + ".database", Description: database},
+		{Label: prefix + ".secret", Description: "name of credentials (user, password) in the secrets file"},
+	}
+}
+
+func (p *datastoreProber) Enabled(target Target) bool {
+	prefix := "monitor." + p.kind
+	return target.Labels()[prefix+".port"] != "" || target.Labels()[prefix] == "true"
+}
+
+func (p *datastoreProber) Probe(ctx context.Context, target Target) []domain.ProbeResult {
+	check := p.check(ctx, target)
+	return []domain.ProbeResult{{
+		Type:    p.Name(),
+		Target:  check.Target,
+		Status:  check.Status,
+		Latency: check.Latency,
+		Failure: check.Failure,
+		Error:   check.Error,
+		Details: check,
+	}}
+}
+
+func (p *datastoreProber) check(ctx context.Context, target Target) domain.DatastoreCheck {
+	labels := target.Labels()
+	prefix := "monitor." + p.kind
+	port := labels[prefix+".port"]
+	if port == "" {
+		port = datastoreDefaultPorts[p.kind]
+	}
+	addr := net.JoinHostPort(target.IP, port)
+
+	creds := Credentials{Database: labels[prefix+".database"]}
+	if name := labels[prefix+".secret"]; name != "" {
+		secret, ok := p.secrets[name]
+		if !ok {
+			return domain.DatastoreCheck{
+				Kind:    p.kind,
+				Target:  addr,
+				Failure: domain.FailureError,
+				Error:   "secret " + name + " not found",
+			}
+		}
+		if secret.Database == "" {
+			secret.Database = creds.Database
+		}
+		creds = secret
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	check := domain.DatastoreCheck{Kind: p.kind, Target: addr}
+	start := time.Now()
+	var err error
+	switch p.kind {
+	case DatastorePostgres:
+		err = checkPostgres(ctx, addr, creds)
+	case DatastoreRedis:
+		err = checkRedis(ctx, addr, creds)
+	case DatastoreAMQP:
+		err = checkAMQP(ctx, addr, creds)
+	default:
+		err = errors.New("unknown datastore " + p.kind)
+	}
+	check.Latency = time.Since(start).Seconds()
+	if err != nil {
+		check.Failure = FailureMode(err)
+		check.Error = redactCredentials(err.Error(), creds)
+		return check
+	}
+	check.Status = true
+	return check
+}
+
+// redactCredentials убирает из сообщения об ошибке пароль из секрета: драйверы
+// иногда включают в ошибку строку подключения, а ошибка уходит с результатом.
+func redactCredentials(msg string, creds Credentials) string {
+	if creds.Password == "" {
+		return msg
+	}
+	// В URL пароль экранирован, поэтому заменяем и эту форму
+	userinfo := url.UserPassword("", creds.Password).String()
+	for _, value := range []string{creds.Password, strings.TrimPrefix(userinfo, ":")} {
+		msg = strings.ReplaceAll(msg, value, "***")
+	}
+	return msg
+}
+
+// checkPostgres проходит startup-последовательность с аутентификацией и выполняет SELECT 1.
+func checkPostgres(ctx context.Context, target string, creds Credentials) error {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		Host:     target,
+		Path:     "/" + creds.Database,
+		RawQuery: "sslmode=disable",
+	}
+	if creds.User != "" {
+		dsn.User = url.UserPassword(creds.User, creds.Password)
+	}
+
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var one int
+	return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// checkRedis отправляет AUTH (если заданы учётные данные) и PING по протоколу RESP.
+func checkRedis(ctx context.Context, target string, creds Credentials) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+
+	if creds.Password != "" {
+		args := []string{"AUTH", creds.Password}
+		if creds.User != "" {
+			args = []string{"AUTH", creds.User, creds.Password}
+		}
+		if _, err := redisCommand(conn, reader, args...); err != nil {
+			return err
+		}
+	}
+
+	reply, err := redisCommand(conn, reader, "PING")
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected PING reply %q", reply)
+	}
+	return nil
+}
+
+func redisCommand(conn net.Conn, reader *bufio.Reader, args ...string) (string, error) {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return "", err
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "-") {
+		return "", errors.New(strings.TrimPrefix(line, "-"))
+	}
+	return strings.TrimPrefix(line, "+"), nil
+}
+
+// checkAMQP открывает AMQP-соединение (handshake и аутентификация) и сразу закрывает его.
+func checkAMQP(ctx context.Context, target string, creds Credentials) error {
+	uri := url.URL{Scheme: "amqp", Host: target, Path: "/" + creds.Database}
+	if creds.User != "" {
+		uri.User = url.UserPassword(creds.User, creds.Password)
+	}
+
+	timeout := time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	conn, err := amqp.DialConfig(uri.String(), amqp.Config{
+		Dial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.DialTimeout(network, addr, timeout)
+			if err != nil {
+				return nil, err
+			}
+			// Ограничиваем и сам handshake
+			conn.SetDeadline(time.Now().Add(timeout))
+			return conn, nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
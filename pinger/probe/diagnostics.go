@@ -0,0 +1,133 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"pinger/domain"
+)
+
+// DockerDNS — встроенный DNS Docker, доступный в пользовательских сетях.
+const DockerDNS = "127.0.0.11:53"
+
+// Traceroute прокладывает путь до ip через системный traceroute, по одному
+// запросу на хоп с ожиданием ответа не дольше секунды.
+func Traceroute(ctx context.Context, ip string, maxHops int) (domain.Traceroute, error) {
+	args := []string{"-n", "-q", "1", "-w", "1", "-m", strconv.Itoa(maxHops)}
+	if strings.Contains(ip, ":") {
+		args = append(args, "-6")
+	}
+	out, err := exec.CommandContext(ctx, "traceroute", append(args, ip)...).Output()
+	if err != nil && len(out) == 0 {
+		return domain.Traceroute{}, err
+	}
+
+	hops := parseTraceroute(string(out))
+	return domain.Traceroute{Hops: hops, Reached: reached(hops, ip)}, nil
+}
+
+// reached сообщает, ответила ли цель на последнем хопе. Адреса сравниваются
+// как IP: traceroute может записать IPv6-адрес не так, как он был передан.
+func reached(hops []domain.TracerouteHop, ip string) bool {
+	if len(hops) == 0 {
+		return false
+	}
+	last := net.ParseIP(hops[len(hops)-1].Address)
+	return last != nil && last.Equal(net.ParseIP(ip))
+}
+
+// parseTraceroute разбирает вывод traceroute -n -q 1:
+//
+//	traceroute to 172.18.0.3 (172.18.0.3), 15 hops max, 46 byte packets
+//	 1  172.18.0.1  0.058 ms
+//	 2  *
+func parseTraceroute(out string) []domain.TracerouteHop {
+	var hops []domain.TracerouteHop
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ttl, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		hop := domain.TracerouteHop{TTL: ttl}
+		for i, field := range fields[1:] {
+			if field == "ms" && i > 0 {
+				if rtt, err := strconv.ParseFloat(fields[i], 64); err == nil && hop.RTT == 0 {
+					hop.RTT = rtt / 1000
+				}
+				continue
+			}
+			if hop.Address == "" && net.ParseIP(field) != nil {
+				hop.Address = field
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// Заголовки IP и ICMP, которые ping не учитывает в размере данных.
+const (
+	ipv4Overhead = 20 + 8
+	ipv6Overhead = 40 + 8
+)
+
+// maxMTU — верхняя граница поиска, jumbo-кадры.
+const maxMTU = 9000
+
+// PathMTU ищет двоичным поиском наибольший пакет, который доходит до ip без
+// фрагментации. Нужен ping из iputils: busybox не умеет запрещать фрагментацию.
+func PathMTU(ctx context.Context, ip string) (int, error) {
+	overhead := ipv4Overhead
+	if strings.Contains(ip, ":") {
+		overhead = ipv6Overhead
+	}
+
+	if !pingSize(ctx, ip, 0) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("target does not answer ICMP echo")
+	}
+	lo, hi := 0, maxMTU-overhead
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if pingSize(ctx, ip, mid) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+	return lo + overhead, nil
+}
+
+// pingSize отправляет один ICMP echo с size байтами данных и запретом фрагментации.
+func pingSize(ctx context.Context, ip string, size int) bool {
+	args := []string{"-c", "1", "-W", "1", "-M", "do", "-s", strconv.Itoa(size)}
+	if strings.Contains(ip, ":") {
+		args = append(args, "-6")
+	}
+	return exec.CommandContext(ctx, "ping", append(args, ip)...).Run() == nil
+}
+
+// Lookup разрешает name через DNS-сервер server, минуя resolv.conf.
+func Lookup(ctx context.Context, server, name string) ([]string, error) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	return resolver.LookupHost(ctx, name)
+}
@@ -0,0 +1,85 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+
+	"pinger/domain"
+)
+
+// ms переводит миллисекунды в секунды так же, как parseTraceroute.
+func ms(v float64) float64 { return v / 1000 }
+
+func TestParseTraceroute(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []domain.TracerouteHop
+	}{
+		{
+			name: "empty output",
+			out:  "",
+			want: nil,
+		},
+		{
+			name: "ipv4 path",
+			out: "traceroute to 172.18.0.3 (172.18.0.3), 15 hops max, 46 byte packets\n" +
+				" 1  172.18.0.1  0.058 ms\n" +
+				" 2  *\n" +
+				" 3  172.18.0.3  1.500 ms\n",
+			want: []domain.TracerouteHop{
+				{TTL: 1, Address: "172.18.0.1", RTT: ms(0.058)},
+				{TTL: 2},
+				{TTL: 3, Address: "172.18.0.3", RTT: ms(1.5)},
+			},
+		},
+		{
+			name: "ipv6 hop",
+			out: "traceroute to fd00::3 (fd00::3), 15 hops max, 80 byte packets\n" +
+				" 1  fd00::3  0.120 ms\n",
+			want: []domain.TracerouteHop{{TTL: 1, Address: "fd00::3", RTT: ms(0.12)}},
+		},
+		{
+			name: "first rtt wins",
+			out:  " 1  10.0.0.1  0.500 ms  0.700 ms\n",
+			want: []domain.TracerouteHop{{TTL: 1, Address: "10.0.0.1", RTT: ms(0.5)}},
+		},
+		{
+			name: "noise lines are skipped",
+			out:  "traceroute: warning: multiple interfaces found\nnot a hop line\n 1\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTraceroute(tt.out)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTraceroute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReached(t *testing.T) {
+	tests := []struct {
+		name string
+		hops []domain.TracerouteHop
+		ip   string
+		want bool
+	}{
+		{"no hops", nil, "172.18.0.3", false},
+		{"last hop is target", []domain.TracerouteHop{{TTL: 1, Address: "172.18.0.3"}}, "172.18.0.3", true},
+		{"last hop is gateway", []domain.TracerouteHop{{TTL: 1, Address: "172.18.0.1"}}, "172.18.0.3", false},
+		{"last hop timed out", []domain.TracerouteHop{{TTL: 1, Address: "172.18.0.1"}, {TTL: 2}}, "172.18.0.3", false},
+		{"ipv6 in other notation", []domain.TracerouteHop{{TTL: 1, Address: "fd00::3"}}, "fd00:0:0:0:0:0:0:3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reached(tt.hops, tt.ip); got != tt.want {
+				t.Errorf("reached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"pinger/domain"
+)
+
+// Метки контейнера для проверки по протоколу grpc.health.v1.Health.
+// Проверка выполняется, только если задан monitor.grpc.port.
+const (
+	LabelGRPCPort = "monitor.grpc.port"
+	// LabelGRPCService — имена сервисов через запятую; пустое имя — состояние сервера целиком.
+	LabelGRPCService  = "monitor.grpc.service"
+	LabelGRPCTLS      = "monitor.grpc.tls"
+	LabelGRPCInsecure = "monitor.grpc.insecure"
+	LabelGRPCTimeout  = "monitor.grpc.timeout"
+)
+
+type grpcProber struct {
+	timeout time.Duration
+}
+
+// NewGRPC создаёт проверку по протоколу grpc.health.v1; контейнер включает её меткой monitor.grpc.port.
+func NewGRPC(timeout time.Duration) Prober {
+	return &grpcProber{timeout: timeout}
+}
+
+func (p *grpcProber) Name() string { return "grpc" }
+
+func (p *grpcProber) Schema() []Option {
+	return []Option{
+		{Label: LabelGRPCPort, Description: "port of the gRPC server, enables the probe"},
+		{Label: LabelGRPCService, Description: "comma-separated service names, empty for the whole server"},
+		{Label: LabelGRPCTLS, Description: "use TLS", Default: "false"},
+		{Label: LabelGRPCInsecure, Description: "skip TLS certificate verification", Default: "false"},
+		{Label: LabelGRPCTimeout, Description: "timeout in milliseconds"},
+	}
+}
+
+func (p *grpcProber) Enabled(target Target) bool {
+	return target.Labels()[LabelGRPCPort] != ""
+}
+
+func (p *grpcProber) Probe(ctx context.Context, target Target) []domain.ProbeResult {
+	var results []domain.ProbeResult
+	for _, check := range p.check(ctx, target) {
+		name := check.Target
+		if check.Service != "" {
+			name += "/" + check.Service
+		}
+		results = append(results, domain.ProbeResult{
+			Type:    p.Name(),
+			Target:  name,
+			Status:  check.Status,
+			Latency: check.Latency,
+			Failure: check.Failure,
+			Error:   check.Error,
+			Details: check,
+		})
+	}
+	return results
+}
+
+// check вызывает Health/Check для каждого сервиса из меток контейнера.
+func (p *grpcProber) check(ctx context.Context, target Target) []domain.GRPCCheck {
+	labels := target.Labels()
+	port := labels[LabelGRPCPort]
+	if port == "" {
+		return nil
+	}
+	addr := net.JoinHostPort(target.IP, port)
+
+	creds := insecure.NewCredentials()
+	if labels[LabelGRPCTLS] == "true" {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: labels[LabelGRPCInsecure] == "true"})
+	}
+
+	timeout := labelTimeout(labels, LabelGRPCTimeout, p.timeout)
+
+	services := []string{""}
+	if value, ok := labels[LabelGRPCService]; ok {
+		services = nil
+		for _, name := range strings.Split(value, ",") {
+			services = append(services, strings.TrimSpace(name))
+		}
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return []domain.GRPCCheck{{
+			Target:  addr,
+			Failure: domain.FailureError,
+			Error:   err.Error(),
+		}}
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	checks := make([]domain.GRPCCheck, 0, len(services))
+	for _, service := range services {
+		checks = append(checks, checkGRPC(ctx, client, addr, service, timeout))
+	}
+	return checks
+}
+
+func checkGRPC(ctx context.Context, client healthpb.HealthClient, target, service string, timeout time.Duration) domain.GRPCCheck {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	check := domain.GRPCCheck{Target: target, Service: service}
+	start := time.Now()
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	check.Latency = time.Since(start).Seconds()
+	if err != nil {
+		check.ServingStatus = healthpb.HealthCheckResponse_UNKNOWN.String()
+		check.Error = err.Error()
+		switch status.Code(err) {
+		case codes.DeadlineExceeded:
+			check.Failure = domain.FailureTimeout
+		case codes.Unimplemented, codes.NotFound:
+			// Сервер не реализует health или не знает указанный сервис
+			check.Failure = domain.FailureUnexpectedResponse
+		default:
+			check.Failure = domain.FailureError
+		}
+		return check
+	}
+
+	check.ServingStatus = resp.GetStatus().String()
+	check.Status = resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+	if !check.Status {
+		check.Failure = domain.FailureUnexpectedResponse
+	}
+	return check
+}
@@ -0,0 +1,266 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pinger/domain"
+)
+
+// Метки контейнера, включающие HTTP(S)-проверку. Проверка выполняется,
+// только если задан monitor.http.path.
+const (
+	LabelHTTPPath     = "monitor.http.path"
+	LabelHTTPPort     = "monitor.http.port"
+	LabelHTTPScheme   = "monitor.http.scheme"
+	LabelHTTPMethod   = "monitor.http.method"
+	LabelHTTPHost     = "monitor.http.host"
+	LabelHTTPExpect   = "monitor.http.expect"
+	LabelHTTPBody     = "monitor.http.body"
+	LabelHTTPInsecure = "monitor.http.insecure"
+	LabelHTTPTimeout  = "monitor.http.timeout"
+)
+
+// maxHTTPBody ограничивает объём тела ответа, читаемого для проверки.
+const maxHTTPBody = 1 << 20
+
+type httpProber struct {
+	timeout time.Duration
+}
+
+// NewHTTP создаёт HTTP(S)-проверку; контейнер включает её меткой monitor.http.path.
+func NewHTTP(timeout time.Duration) Prober {
+	return &httpProber{timeout: timeout}
+}
+
+func (p *httpProber) Name() string { return "http" }
+
+func (p *httpProber) Schema() []Option {
+	return []Option{
+		{Label: LabelHTTPPath, Description: "request path, enables the probe"},
+		{Label: LabelHTTPPort, Description: "port, defaults to 80/443 or the first exposed TCP port"},
+		{Label: LabelHTTPScheme, Description: "http or https", Default: "http"},
+		{Label: LabelHTTPMethod, Description: "request method", Default: http.MethodGet},
+		{Label: LabelHTTPHost, Description: "Host header"},
+		{Label: LabelHTTPExpect, Description: "expected status: 200, 200,204 or 2xx", Default: "2xx"},
+		{Label: LabelHTTPBody, Description: "regular expression the body must match"},
+		{Label: LabelHTTPInsecure, Description: "skip TLS certificate verification", Default: "false"},
+		{Label: LabelHTTPTimeout, Description: "timeout in milliseconds"},
+	}
+}
+
+func (p *httpProber) Enabled(target Target) bool {
+	_, ok := target.Labels()[LabelHTTPPath]
+	return ok
+}
+
+func (p *httpProber) Probe(ctx context.Context, target Target) []domain.ProbeResult {
+	check := p.check(ctx, target)
+	return []domain.ProbeResult{{
+		Type:    p.Name(),
+		Target:  check.URL,
+		Status:  check.Status,
+		Latency: check.Total,
+		Failure: check.Failure,
+		Error:   check.Error,
+		Details: check,
+	}}
+}
+
+// check выполняет HTTP-запрос к цели согласно меткам контейнера.
+func (p *httpProber) check(ctx context.Context, target Target) *domain.HTTPCheck {
+	container, ip := target.Container, target.IP
+	labels := container.Labels
+	path := labels[LabelHTTPPath]
+
+	scheme := labels[LabelHTTPScheme]
+	if scheme == "" {
+		scheme = "http"
+	}
+	port := labels[LabelHTTPPort]
+	if port == "" {
+		port = defaultHTTPPort(container, scheme)
+	}
+	method := labels[LabelHTTPMethod]
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	url := scheme + "://" + net.JoinHostPort(ip, port) + path
+	check := &domain.HTTPCheck{URL: url}
+
+	ctx, cancel := context.WithTimeout(ctx, labelTimeout(labels, LabelHTTPTimeout, p.timeout))
+	defer cancel()
+
+	var (
+		start                      = time.Now()
+		dnsStart, connStart, tlsAt time.Time
+	)
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { check.DNS = time.Since(dnsStart).Seconds() },
+		ConnectStart:      func(string, string) { connStart = time.Now() },
+		ConnectDone:       func(string, string, error) { check.Connect = time.Since(connStart).Seconds() },
+		TLSHandshakeStart: func() { tlsAt = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { check.TLS = time.Since(tlsAt).Seconds() },
+		GotFirstResponseByte: func() {
+			check.TTFB = time.Since(start).Seconds()
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), method, url, nil)
+	if err != nil {
+		check.Failure = domain.FailureError
+		check.Error = err.Error()
+		return check
+	}
+	tlsConfig := &tls.Config{ServerName: ip}
+	if host := labels[LabelHTTPHost]; host != "" {
+		req.Host = host
+		// Запрос идёт на IP, а сертификат и SNI — по имени из метки
+		tlsConfig.ServerName = host
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			tlsConfig.ServerName = name
+		}
+	}
+	// Сертификат проверяется вручную, чтобы срок его действия попал в
+	// результат и тогда, когда проверку он не прошёл
+	insecure := labels[LabelHTTPInsecure] == "true"
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) > 0 {
+			expiry := state.PeerCertificates[0].NotAfter
+			check.CertExpiry = &expiry
+		}
+		if insecure {
+			return nil
+		}
+		return verifyPeer(state.PeerCertificates, tlsConfig.ServerName)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig:   tlsConfig,
+		},
+		// Редиректы не выполняем: ожидаемый код задаётся меткой
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		check.Total = time.Since(start).Seconds()
+		check.Failure = FailureMode(err)
+		check.Error = err.Error()
+		return check
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	check.Total = time.Since(start).Seconds()
+	check.StatusCode = resp.StatusCode
+	if err != nil {
+		check.Failure = domain.FailureError
+		check.Error = err.Error()
+		return check
+	}
+
+	if !statusMatches(labels[LabelHTTPExpect], resp.StatusCode) {
+		check.Failure = domain.FailureUnexpectedResponse
+		check.Error = "unexpected status " + strconv.Itoa(resp.StatusCode)
+		return check
+	}
+	if pattern := labels[LabelHTTPBody]; pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			check.Failure = domain.FailureError
+			check.Error = "invalid body pattern: " + err.Error()
+			return check
+		}
+		if !re.Match(body) {
+			check.Failure = domain.FailureUnexpectedResponse
+			check.Error = "body does not match " + pattern
+			return check
+		}
+	}
+
+	check.Status = true
+	return check
+}
+
+// statusMatches сверяет код ответа с ожиданием вида "200", "200,204" или "2xx".
+// Без ожидания успешным считается любой код 2xx.
+func statusMatches(expect string, code int) bool {
+	if strings.TrimSpace(expect) == "" {
+		expect = "2xx"
+	}
+	for _, item := range strings.Split(expect, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if len(item) == 3 && strings.HasSuffix(item, "xx") {
+			if strconv.Itoa(code/100) == item[:1] {
+				return true
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(item); err == nil && n == code {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultHTTPPort выбирает порт, если он не задан меткой: стандартный для схемы,
+// если контейнер его открывает, иначе первый открытый TCP-порт.
+func defaultHTTPPort(container domain.Container, scheme string) string {
+	standard := 80
+	if scheme == "https" {
+		standard = 443
+	}
+	first := 0
+	for _, port := range container.Ports {
+		if port.Protocol != "tcp" {
+			continue
+		}
+		if port.Port == standard {
+			return strconv.Itoa(standard)
+		}
+		if first == 0 {
+			first = port.Port
+		}
+	}
+	if first != 0 {
+		return strconv.Itoa(first)
+	}
+	return strconv.Itoa(standard)
+}
+
+// verifyPeer проверяет цепочку сертификатов сервера так же, как crypto/tls
+// при выключенном InsecureSkipVerify.
+func verifyPeer(certs []*x509.Certificate, serverName string) error {
+	if len(certs) == 0 {
+		return errors.New("tls: server sent no certificates")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return &tls.CertificateVerificationError{UnverifiedCertificates: certs, Err: err}
+	}
+	return nil
+}
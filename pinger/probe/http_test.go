@@ -0,0 +1,70 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pinger/domain"
+)
+
+func TestStatusMatches(t *testing.T) {
+	tests := []struct {
+		expect string
+		code   int
+		want   bool
+	}{
+		{"", 200, true},
+		{"", 204, true},
+		{"", 302, false},
+		{"  ", 500, false},
+		{"200", 200, true},
+		{"200", 201, false},
+		{"200,204", 204, true},
+		{" 200 , 404 ", 404, true},
+		{"2xx", 299, true},
+		{"3XX", 301, true},
+		{"5xx", 200, false},
+		{"2xx,404", 404, true},
+		{"20x", 200, false},
+		{"xx", 200, false},
+		{"abc", 200, false},
+	}
+
+	for _, tt := range tests {
+		if got := statusMatches(tt.expect, tt.code); got != tt.want {
+			t.Errorf("statusMatches(%q, %d) = %v, want %v", tt.expect, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestCertExpiryWithUntrustedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, insecure := range []string{"false", "true"} {
+		target := Target{
+			IP: host,
+			Container: domain.Container{ContainerMeta: domain.ContainerMeta{Labels: map[string]string{
+				LabelHTTPPath:     "/",
+				LabelHTTPScheme:   "https",
+				LabelHTTPPort:     port,
+				LabelHTTPInsecure: insecure,
+			}}},
+		}
+		check := NewHTTP(5*time.Second).(*httpProber).check(context.Background(), target)
+		if check.CertExpiry == nil || !check.CertExpiry.Equal(server.Certificate().NotAfter) {
+			t.Errorf("insecure=%s: CertExpiry = %v, want %v", insecure, check.CertExpiry, server.Certificate().NotAfter)
+		}
+		if wantStatus := insecure == "true"; check.Status != wantStatus {
+			t.Errorf("insecure=%s: Status = %v, want %v (error %q)", insecure, check.Status, wantStatus, check.Error)
+		}
+	}
+}
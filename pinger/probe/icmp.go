@@ -0,0 +1,81 @@
+package probe
+
+import (
+	"context"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"pinger/domain"
+)
+
+// LabelICMP = "false" отключает ICMP-проверку контейнера.
+const LabelICMP = "monitor.icmp"
+
+type icmpProber struct {
+	timeout time.Duration
+}
+
+// NewICMP создаёт проверку доступности через ping.
+func NewICMP(timeout time.Duration) Prober {
+	return &icmpProber{timeout: timeout}
+}
+
+func (p *icmpProber) Name() string { return "icmp" }
+
+func (p *icmpProber) Schema() []Option {
+	return []Option{
+		{Label: LabelICMP, Description: "set to false to disable ICMP echo", Default: "true"},
+	}
+}
+
+func (p *icmpProber) Enabled(target Target) bool {
+	return target.Labels()[LabelICMP] != "false"
+}
+
+func (p *icmpProber) Probe(ctx context.Context, target Target) []domain.ProbeResult {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	latency, err := Ping(ctx, target.IP)
+	result := domain.ProbeResult{
+		Type:    p.Name(),
+		Target:  target.IP,
+		Status:  err == nil,
+		Latency: latency,
+	}
+	if err != nil {
+		result.Failure = domain.FailureTimeout
+		result.Error = err.Error()
+	}
+	return []domain.ProbeResult{result}
+}
+
+// Ping отправляет один ICMP echo через системный ping и возвращает время в секундах.
+func Ping(ctx context.Context, ip string) (float64, error) {
+	// ping принимает таймаут только в целых секундах
+	wait := 1
+	if deadline, ok := ctx.Deadline(); ok {
+		wait = int(math.Max(1, math.Ceil(time.Until(deadline).Seconds())))
+	}
+
+	args := []string{"-c", "1", "-W", strconv.Itoa(wait)}
+	if strings.Contains(ip, ":") {
+		args = append(args, "-6")
+	}
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, "ping", append(args, ip)...)
+	err := cmd.Run()
+	return time.Since(start).Seconds(), err
+}
+
+// Family возвращает семейство адреса ip.
+func Family(ip string) string {
+	if strings.Contains(ip, ":") {
+		return domain.FamilyIPv6
+	}
+	return domain.FamilyIPv4
+}
@@ -0,0 +1,253 @@
+package probe
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"pinger/domain"
+)
+
+// Метки контейнера, настраивающие проверки портов.
+const (
+	// LabelTCP = "false" отключает TCP-проверки контейнера.
+	LabelTCP = "monitor.tcp"
+	// LabelTCPPorts — список TCP-портов через запятую вместо портов из метаданных.
+	LabelTCPPorts = "monitor.tcp.ports"
+	// LabelUDPPorts — список UDP-портов; UDP проверяется только по явному запросу.
+	LabelUDPPorts = "monitor.udp.ports"
+	// LabelUDPSend — отправляемые данные; префикс "hex:" задаёт их в шестнадцатеричном виде.
+	LabelUDPSend = "monitor.udp.send"
+	// LabelUDPExpect — регулярное выражение, которому должен соответствовать ответ.
+	LabelUDPExpect = "monitor.udp.expect"
+)
+
+type tcpProber struct {
+	timeout time.Duration
+}
+
+// NewTCP создаёт проверку TCP connect по портам контейнера.
+func NewTCP(timeout time.Duration) Prober {
+	return &tcpProber{timeout: timeout}
+}
+
+func (p *tcpProber) Name() string { return "tcp" }
+
+func (p *tcpProber) Schema() []Option {
+	return []Option{
+		{Label: LabelTCP, Description: "set to false to disable TCP connect probes", Default: "true"},
+		{Label: LabelTCPPorts, Description: "comma-separated TCP ports instead of exposed ports"},
+	}
+}
+
+func (p *tcpProber) Enabled(target Target) bool {
+	return target.Labels()[LabelTCP] != "false" && len(p.ports(target)) > 0
+}
+
+func (p *tcpProber) ports(target Target) []int {
+	ports, ok := parsePorts(target.Labels()[LabelTCPPorts])
+	if ok {
+		return ports
+	}
+	for _, port := range target.Container.Ports {
+		if port.Protocol == "tcp" {
+			ports = append(ports, port.Port)
+		}
+	}
+	return ports
+}
+
+func (p *tcpProber) Probe(ctx context.Context, target Target) []domain.ProbeResult {
+	var results []domain.ProbeResult
+	for _, port := range p.ports(target) {
+		results = append(results, portResult(p.Name(), target.IP, DialTCP(ctx, target.IP, port, p.timeout)))
+	}
+	return results
+}
+
+type udpProber struct {
+	timeout time.Duration
+}
+
+// NewUDP создаёт проверку UDP-портов; она выполняется только по метке monitor.udp.ports.
+func NewUDP(timeout time.Duration) Prober {
+	return &udpProber{timeout: timeout}
+}
+
+func (p *udpProber) Name() string { return "udp" }
+
+func (p *udpProber) Schema() []Option {
+	return []Option{
+		{Label: LabelUDPPorts, Description: "comma-separated UDP ports to probe"},
+		{Label: LabelUDPSend, Description: "payload to send, hex: prefix for binary"},
+		{Label: LabelUDPExpect, Description: "regular expression the response must match"},
+	}
+}
+
+func (p *udpProber) Enabled(target Target) bool {
+	_, ok := parsePorts(target.Labels()[LabelUDPPorts])
+	return ok
+}
+
+func (p *udpProber) Probe(ctx context.Context, target Target) []domain.ProbeResult {
+	labels := target.Labels()
+	ports, _ := parsePorts(labels[LabelUDPPorts])
+
+	payload, err := udpPayload(labels[LabelUDPSend])
+	if err != nil {
+		log.Printf("Invalid %s label on container %s: %v", LabelUDPSend, target.Container.ID, err)
+	}
+	var expect *regexp.Regexp
+	if pattern := labels[LabelUDPExpect]; pattern != "" {
+		if expect, err = regexp.Compile(pattern); err != nil {
+			log.Printf("Invalid %s label on container %s: %v", LabelUDPExpect, target.Container.ID, err)
+		}
+	}
+
+	var results []domain.ProbeResult
+	for _, port := range ports {
+		check := dialUDP(ctx, target.IP, port, payload, expect, p.timeout)
+		results = append(results, portResult(p.Name(), target.IP, check))
+	}
+	return results
+}
+
+func portResult(probeType, ip string, check domain.PortCheck) domain.ProbeResult {
+	return domain.ProbeResult{
+		Type:    probeType,
+		Target:  net.JoinHostPort(ip, strconv.Itoa(check.Port)),
+		Status:  check.Status,
+		Latency: check.Latency,
+		Failure: check.Failure,
+		Error:   check.Error,
+		Details: check,
+	}
+}
+
+// DialTCP устанавливает TCP-соединение с host:port за отведённое время.
+func DialTCP(ctx context.Context, host string, port int, timeout time.Duration) domain.PortCheck {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	check := domain.PortCheck{Port: port, Protocol: "tcp"}
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	check.Latency = time.Since(start).Seconds()
+	if err != nil {
+		check.Failure = FailureMode(err)
+		check.Error = err.Error()
+		return check
+	}
+	conn.Close()
+	check.Status = true
+	return check
+}
+
+// dialUDP отправляет датаграмму и ждёт ответа. Без ожидаемого ответа порт
+// считается доступным, если до таймаута не пришло ICMP port unreachable.
+func dialUDP(ctx context.Context, host string, port int, payload []byte, expect *regexp.Regexp, timeout time.Duration) domain.PortCheck {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	check := domain.PortCheck{Port: port, Protocol: "udp"}
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		check.Failure = FailureMode(err)
+		check.Error = err.Error()
+		return check
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(payload); err != nil {
+		check.Failure = FailureMode(err)
+		check.Error = err.Error()
+		return check
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	check.Latency = time.Since(start).Seconds()
+	switch {
+	case err != nil && expect == nil && FailureMode(err) == domain.FailureTimeout:
+		// Тишина для UDP — обычное дело: порт открыт или ответ отфильтрован
+		check.Status = true
+	case err != nil:
+		check.Failure = FailureMode(err)
+		check.Error = err.Error()
+	case expect != nil && !expect.Match(buf[:n]):
+		check.Failure = domain.FailureUnexpectedResponse
+		check.Error = "response does not match " + expect.String()
+	default:
+		check.Status = true
+	}
+	return check
+}
+
+// FailureMode сводит сетевую ошибку к одной из категорий domain.Failure*.
+func FailureMode(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return domain.FailureRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return domain.FailureTimeout
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return domain.FailureUnreachable
+	default:
+		return domain.FailureError
+	}
+}
+
+// parsePorts разбирает список портов из метки; ok=false, если метка не задана.
+func parsePorts(value string) ([]int, bool) {
+	if strings.TrimSpace(value) == "" {
+		return nil, false
+	}
+	var ports []int
+	for _, item := range strings.Split(value, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || port <= 0 || port > 65535 {
+			log.Printf("Ignoring invalid port %q", item)
+			continue
+		}
+		ports = append(ports, port)
+	}
+	return ports, true
+}
+
+func udpPayload(value string) ([]byte, error) {
+	if hexValue, ok := strings.CutPrefix(value, "hex:"); ok {
+		return hex.DecodeString(hexValue)
+	}
+	if value == "" {
+		// Пустая датаграмма тоже вызывает ICMP port unreachable у закрытого порта
+		return []byte{}, nil
+	}
+	return []byte(value), nil
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, errors.New("value must be positive")
+	}
+	return n, nil
+}
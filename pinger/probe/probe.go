@@ -0,0 +1,183 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"pinger/domain"
+)
+
+// LabelProbes — явный список проверок контейнера через запятую, например "icmp,http".
+// Без метки выполняются все зарегистрированные проверки, которые контейнер включил своими метками.
+const LabelProbes = "monitor.probes"
+
+// Target — адрес контейнера в конкретной сети, который проверяет Prober.
+type Target struct {
+	Container domain.Container
+	Network   string
+	IP        string
+}
+
+// Labels возвращает метки контейнера цели.
+func (t Target) Labels() map[string]string {
+	return t.Container.Labels
+}
+
+// Option описывает один параметр проверки, задаваемый меткой контейнера.
+type Option struct {
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Default     string `json:"default,omitempty"`
+}
+
+// Prober — проверка одного типа. Реализации должны быть безопасны для
+// одновременного вызова из нескольких воркеров.
+type Prober interface {
+	// Name — уникальное имя типа проверки, оно же ProbeResult.Type.
+	Name() string
+	// Schema перечисляет метки, которыми настраивается проверка.
+	Schema() []Option
+	// Enabled сообщает, запросила ли цель эту проверку своими метками.
+	Enabled(target Target) bool
+	// Probe выполняет проверку; одна цель может дать несколько результатов (например, по портам).
+	Probe(ctx context.Context, target Target) []domain.ProbeResult
+}
+
+// Registry хранит доступные проверки и выбирает их для цели.
+type Registry struct {
+	mu      sync.RWMutex
+	probers map[string]Prober
+	order   []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{probers: make(map[string]Prober)}
+}
+
+// Register добавляет проверку; имена должны быть уникальны.
+func (r *Registry) Register(p Prober) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := p.Name()
+	if name == "" {
+		return errors.New("prober name is empty")
+	}
+	if _, ok := r.probers[name]; ok {
+		return errors.New("prober " + name + " is already registered")
+	}
+	r.probers[name] = p
+	r.order = append(r.order, name)
+	return nil
+}
+
+func (r *Registry) Get(name string) (Prober, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.probers[name]
+	return p, ok
+}
+
+// Names возвращает имена проверок в порядке регистрации.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string(nil), r.order...)
+}
+
+// Schemas возвращает параметры всех зарегистрированных проверок.
+func (r *Registry) Schemas() map[string][]Option {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schemas := make(map[string][]Option, len(r.probers))
+	for name, p := range r.probers {
+		schemas[name] = p.Schema()
+	}
+	return schemas
+}
+
+// Select выбирает проверки для цели: из метки monitor.probes, если она задана,
+// иначе все проверки, включённые метками цели.
+func (r *Registry) Select(target Target) []Prober {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var selected []Prober
+	if value, ok := target.Labels()[LabelProbes]; ok {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if p, ok := r.probers[name]; ok {
+				selected = append(selected, p)
+			} else if name != "" {
+				log.Printf("Unknown probe %q requested by container %s", name, target.Container.ID)
+			}
+		}
+		return selected
+	}
+
+	for _, name := range r.order {
+		if p := r.probers[name]; p.Enabled(target) {
+			selected = append(selected, p)
+		}
+	}
+	return selected
+}
+
+// Run выполняет выбранные для цели проверки последовательно.
+func (r *Registry) Run(ctx context.Context, target Target) []domain.ProbeResult {
+	var results []domain.ProbeResult
+	for _, p := range r.Select(target) {
+		results = append(results, p.Probe(ctx, target)...)
+	}
+	return results
+}
+
+// Defaults — общие параметры встроенных проверок.
+type Defaults struct {
+	// Timeout ограничивает одну проверку (один адрес, порт или запрос).
+	Timeout time.Duration
+	// Secrets — учётные данные для проверок хранилищ, см. LoadSecrets.
+	Secrets map[string]Credentials
+	// ScriptsDir — каталог скриптов Starlark; пустой отключает проверку script.
+	ScriptsDir string
+	// ScriptLimits ограничивает ресурсы скриптов.
+	ScriptLimits ScriptLimits
+}
+
+// Builtin возвращает все встроенные проверки.
+func Builtin(d Defaults) []Prober {
+	if d.Timeout <= 0 {
+		d.Timeout = time.Second
+	}
+	probers := []Prober{
+		NewICMP(d.Timeout),
+		NewTCP(d.Timeout),
+		NewUDP(d.Timeout),
+		NewHTTP(d.Timeout),
+		NewGRPC(d.Timeout),
+		NewDatastore(DatastorePostgres, d.Timeout, d.Secrets),
+		NewDatastore(DatastoreRedis, d.Timeout, d.Secrets),
+		NewDatastore(DatastoreAMQP, d.Timeout, d.Secrets),
+	}
+	if d.ScriptsDir != "" {
+		probers = append(probers, NewScript(d.ScriptsDir, d.ScriptLimits))
+	}
+	return probers
+}
+
+// labelTimeout читает таймаут в миллисекундах из метки или возвращает def.
+func labelTimeout(labels map[string]string, label string, def time.Duration) time.Duration {
+	if value := labels[label]; value != "" {
+		if ms, err := parsePositive(value); err == nil {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return def
+}
@@ -0,0 +1,395 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+
+	"pinger/domain"
+)
+
+// Метки контейнера для пользовательских проверок на Starlark.
+const (
+	// LabelScript — имя файла скрипта в каталоге скриптов, включает проверку.
+	LabelScript        = "monitor.script"
+	LabelScriptTimeout = "monitor.script.timeout"
+)
+
+// ScriptLimits ограничивает ресурсы, доступные скрипту.
+type ScriptLimits struct {
+	// Timeout — общее время выполнения скрипта по умолчанию.
+	Timeout time.Duration
+	// MaxSteps — максимальное число шагов интерпретатора.
+	MaxSteps uint64
+	// MaxCalls — максимальное число вызовов http/tcp за один запуск.
+	MaxCalls int
+	// MaxBody — максимальный размер тела HTTP-ответа, доступного скрипту.
+	MaxBody int64
+	// MaxOutput — максимальный объём вывода print, попадающего в результат.
+	MaxOutput int
+	// MaxMemory — на сколько байт может вырасти куча пингера за время работы
+	// скрипта. Starlark не считает выделения, поэтому предел приблизительный:
+	// рост кучи проверяется раз в memoryCheckInterval, а одна операция
+	// Starlark (повтор строки или списка) сама ограничена 1 ГиБ.
+	MaxMemory uint64
+}
+
+// memoryCheckInterval — период проверки роста кучи во время работы скрипта.
+const memoryCheckInterval = 10 * time.Millisecond
+
+type scriptProber struct {
+	dir    string
+	limits ScriptLimits
+}
+
+// NewScript создаёт проверку, выполняющую скрипты Starlark из каталога dir.
+// Скрипт должен определить функцию check(target); проверка успешна, если
+// check вернула None или True и не завершилась ошибкой (fail, assert).
+//
+// Скрипту доступны только:
+//
+//	http.get(url, headers={}), http.post(url, body="", headers={}) -> struct(status, body, headers)
+//	tcp.connect(host, port) -> latency в секундах
+//	sleep(seconds)
+//	assert(cond, msg="assertion failed")
+//
+// load() и доступ к файловой системе отключены.
+func NewScript(dir string, limits ScriptLimits) Prober {
+	if limits.Timeout <= 0 {
+		limits.Timeout = 10 * time.Second
+	}
+	if limits.MaxSteps == 0 {
+		limits.MaxSteps = 1_000_000
+	}
+	if limits.MaxCalls <= 0 {
+		limits.MaxCalls = 20
+	}
+	if limits.MaxBody <= 0 {
+		limits.MaxBody = 1 << 20
+	}
+	if limits.MaxOutput <= 0 {
+		limits.MaxOutput = 4096
+	}
+	if limits.MaxMemory == 0 {
+		limits.MaxMemory = 64 << 20
+	}
+	return &scriptProber{dir: dir, limits: limits}
+}
+
+func (p *scriptProber) Name() string { return "script" }
+
+func (p *scriptProber) Schema() []Option {
+	return []Option{
+		{Label: LabelScript, Description: "script file name in the scripts directory, enables the probe"},
+		{Label: LabelScriptTimeout, Description: "timeout in milliseconds", Default: strconv.FormatInt(p.limits.Timeout.Milliseconds(), 10)},
+	}
+}
+
+func (p *scriptProber) Enabled(target Target) bool {
+	return target.Labels()[LabelScript] != ""
+}
+
+func (p *scriptProber) Probe(ctx context.Context, target Target) []domain.ProbeResult {
+	name := target.Labels()[LabelScript]
+	result := domain.ProbeResult{Type: p.Name(), Target: name}
+	details := &domain.ScriptCheck{Script: name}
+	result.Details = details
+
+	start := time.Now()
+	err := p.run(ctx, target, name, details)
+	result.Latency = time.Since(start).Seconds()
+	if err != nil {
+		result.Failure = domain.FailureScript
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Failure = domain.FailureTimeout
+		}
+		result.Error = err.Error()
+		return []domain.ProbeResult{result}
+	}
+	result.Status = true
+	return []domain.ProbeResult{result}
+}
+
+func (p *scriptProber) run(ctx context.Context, target Target, name string, details *domain.ScriptCheck) error {
+	// Скрипт берём только из каталога скриптов, без подкаталогов
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return errors.New("invalid script name " + name)
+	}
+	src, err := os.ReadFile(filepath.Join(p.dir, name))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, labelTimeout(target.Labels(), LabelScriptTimeout, p.limits.Timeout))
+	defer cancel()
+
+	host := &scriptHost{ctx: ctx, limits: p.limits}
+	var output bytes.Buffer
+	thread := &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, msg string) {
+			if output.Len() < p.limits.MaxOutput {
+				output.WriteString(msg + "\n")
+			}
+		},
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, errors.New("load is disabled")
+		},
+	}
+	thread.SetMaxExecutionSteps(p.limits.MaxSteps)
+
+	// Прерываем интерпретатор по таймауту и по росту памяти
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		base := heapBytes()
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				thread.Cancel("timeout")
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if heap := heapBytes(); heap > base && heap-base > p.limits.MaxMemory {
+					thread.Cancel(fmt.Sprintf("memory limit of %d bytes exceeded", p.limits.MaxMemory))
+					return
+				}
+			}
+		}
+	}()
+
+	defer func() {
+		details.Steps = thread.ExecutionSteps()
+		details.Output = strings.TrimRight(output.String(), "\n")
+		if len(details.Output) > p.limits.MaxOutput {
+			details.Output = details.Output[:p.limits.MaxOutput]
+		}
+	}()
+
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, name, src, host.predeclared())
+	if err != nil {
+		return scriptError(ctx, err)
+	}
+	check, ok := globals["check"].(starlark.Callable)
+	if !ok {
+		return errors.New("script does not define check(target)")
+	}
+
+	value, err := starlark.Call(thread, check, starlark.Tuple{targetValue(target)}, nil)
+	if err != nil {
+		return scriptError(ctx, err)
+	}
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil
+	case starlark.Bool:
+		if !bool(v) {
+			return errors.New("check returned False")
+		}
+		return nil
+	default:
+		return fmt.Errorf("check returned %s, want bool or None", value.Type())
+	}
+}
+
+// heapBytes возвращает объём кучи процесса, занятый объектами.
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// scriptError убирает из ошибки Starlark трассировку, оставляя сообщение.
+// Прерывание по таймауту возвращается как context.DeadlineExceeded.
+func scriptError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("script %w", context.DeadlineExceeded)
+	}
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Msg)
+	}
+	return err
+}
+
+func targetValue(target Target) starlark.Value {
+	labels := starlark.NewDict(len(target.Labels()))
+	for k, v := range target.Labels() {
+		labels.SetKey(starlark.String(k), starlark.String(v))
+	}
+	return starlarkstruct.FromStringDict(starlark.String("target"), starlark.StringDict{
+		"id":      starlark.String(target.Container.ID),
+		"name":    starlark.String(target.Container.Name),
+		"ip":      starlark.String(target.IP),
+		"network": starlark.String(target.Network),
+		"labels":  labels,
+	})
+}
+
+// scriptHost реализует API, доступное скрипту, с учётом лимитов.
+type scriptHost struct {
+	ctx    context.Context
+	limits ScriptLimits
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (h *scriptHost) predeclared() starlark.StringDict {
+	return starlark.StringDict{
+		"http": &starlarkstruct.Module{Name: "http", Members: starlark.StringDict{
+			"get":  starlark.NewBuiltin("http.get", h.httpGet),
+			"post": starlark.NewBuiltin("http.post", h.httpPost),
+		}},
+		"tcp": &starlarkstruct.Module{Name: "tcp", Members: starlark.StringDict{
+			"connect": starlark.NewBuiltin("tcp.connect", h.tcpConnect),
+		}},
+		"sleep":  starlark.NewBuiltin("sleep", h.sleep),
+		"assert": starlark.NewBuiltin("assert", assertBuiltin),
+	}
+}
+
+func (h *scriptHost) call() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.calls++
+	if h.calls > h.limits.MaxCalls {
+		return fmt.Errorf("too many host calls (limit %d)", h.limits.MaxCalls)
+	}
+	return nil
+}
+
+func (h *scriptHost) httpGet(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var url string
+	var headers *starlark.Dict
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "url", &url, "headers?", &headers); err != nil {
+		return nil, err
+	}
+	return h.doHTTP(http.MethodGet, url, "", headers)
+}
+
+func (h *scriptHost) httpPost(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var url, body string
+	var headers *starlark.Dict
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "url", &url, "body?", &body, "headers?", &headers); err != nil {
+		return nil, err
+	}
+	return h.doHTTP(http.MethodPost, url, body, headers)
+}
+
+func (h *scriptHost) doHTTP(method, url, body string, headers *starlark.Dict) (starlark.Value, error) {
+	if err := h.call(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(h.ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if headers != nil {
+		for _, item := range headers.Items() {
+			key, ok1 := starlark.AsString(item[0])
+			value, ok2 := starlark.AsString(item[1])
+			if !ok1 || !ok2 {
+				return nil, errors.New("headers must be a dict of strings")
+			}
+			req.Header.Set(key, value)
+		}
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, h.limits.MaxBody))
+	if err != nil {
+		return nil, err
+	}
+
+	respHeaders := starlark.NewDict(len(resp.Header))
+	for key := range resp.Header {
+		respHeaders.SetKey(starlark.String(strings.ToLower(key)), starlark.String(resp.Header.Get(key)))
+	}
+	return starlarkstruct.FromStringDict(starlark.String("response"), starlark.StringDict{
+		"status":  starlark.MakeInt(resp.StatusCode),
+		"body":    starlark.String(data),
+		"headers": respHeaders,
+	}), nil
+}
+
+func (h *scriptHost) tcpConnect(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var host string
+	var port int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "host", &host, "port", &port); err != nil {
+		return nil, err
+	}
+	if err := h.call(); err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(h.ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	return starlark.Float(time.Since(start).Seconds()), nil
+}
+
+func (h *scriptHost) sleep(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var seconds starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &seconds); err != nil {
+		return nil, err
+	}
+	f, ok := starlark.AsFloat(seconds)
+	if !ok || f < 0 {
+		return nil, errors.New("sleep: want non-negative number")
+	}
+
+	timer := time.NewTimer(time.Duration(f * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-h.ctx.Done():
+		return nil, h.ctx.Err()
+	case <-timer.C:
+		return starlark.None, nil
+	}
+}
+
+func assertBuiltin(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var cond starlark.Value
+	msg := "assertion failed"
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "cond", &cond, "msg?", &msg); err != nil {
+		return nil, err
+	}
+	if !cond.Truth() {
+		return nil, errors.New(msg)
+	}
+	return starlark.None, nil
+}